@@ -3,19 +3,38 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/rs/zerolog"
+
+	"grafana-db-exporter/internal/changes"
+	"grafana-db-exporter/internal/cleanup"
+	"grafana-db-exporter/internal/commitmsg"
 	"grafana-db-exporter/internal/config"
 	"grafana-db-exporter/internal/git"
 	"grafana-db-exporter/internal/grafana"
+	"grafana-db-exporter/internal/inventory"
+	"grafana-db-exporter/internal/lint"
 	"grafana-db-exporter/internal/logger"
+	"grafana-db-exporter/internal/mirror"
+	"grafana-db-exporter/internal/normalize"
+	"grafana-db-exporter/internal/overlay"
+	"grafana-db-exporter/internal/pullrequest"
+	"grafana-db-exporter/internal/query"
+	"grafana-db-exporter/internal/report"
+	"grafana-db-exporter/internal/secrets"
+	"grafana-db-exporter/internal/signing"
+	"grafana-db-exporter/internal/tagging"
+	"grafana-db-exporter/internal/transform"
 	"grafana-db-exporter/internal/utils"
 )
 
@@ -33,6 +52,11 @@ func main() {
 	setupSignalHandler(cancel)
 
 	if err := run(ctx); err != nil {
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			logger.Log.Info().Int("code", exitErr.code).Str("reason", exitErr.reason).Msg("Grafana DB exporter completed")
+			os.Exit(exitErr.code)
+		}
 		logger.Log.Fatal().Err(err).Msg("Application failed")
 	}
 
@@ -44,10 +68,30 @@ func run(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
+	if cfg.RunID == "" {
+		cfg.RunID = time.Now().UTC().Format("20060102T150405Z")
+	}
+	if err := validateFeatures(cfg); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
 
-	gitClient, err := setupGitClient(cfg)
-	if err != nil {
-		return fmt.Errorf("failed to setup Git client: %w", err)
+	useGit := cfg.OutputMode != config.OutputModeFilesystem
+	runReport := report.New()
+
+	var gitClient *git.Client
+	var mirrors []mirror.Mirror
+	if useGit {
+		gitClient, err = setupGitClient(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("failed to setup Git client: %w", err)
+		}
+
+		if cfg.MirrorsPath != "" {
+			mirrors, err = setupMirrors(gitClient, cfg, runReport)
+			if err != nil {
+				return fmt.Errorf("failed to setup mirrors: %w", err)
+			}
+		}
 	}
 
 	grafanaClient, err := grafana.New(cfg.GrafanaURL, cfg.GrafanaSaToken)
@@ -55,9 +99,18 @@ func run(ctx context.Context) error {
 		return fmt.Errorf("failed to create Grafana client: %w", err)
 	}
 
-	branchName, err := createNewBranch(ctx, gitClient, cfg)
-	if err != nil {
-		return fmt.Errorf("failed to create new branch: %w", err)
+	var branchName string
+	if useGit {
+		branchName, err = prepareBranch(ctx, gitClient, cfg)
+		if err != nil {
+			return fmt.Errorf("failed to prepare branch: %w", err)
+		}
+
+		if cfg.BranchCleanup {
+			if err := cleanupBranches(ctx, gitClient, cfg, branchName); err != nil {
+				logger.Log.Warn().Err(err).Msg("Failed to clean up stale branches")
+			}
+		}
 	}
 
 	dashboards, err := utils.Retry(ctx, cfg, "fetch dashboards", func() ([]grafana.Dashboard, error) {
@@ -68,35 +121,255 @@ func run(ctx context.Context) error {
 	}
 	logger.Log.Info().Int("count", len(dashboards)).Msg("Fetched dashboards")
 
-	if cfg.DeleteMissing {
-		if err := deleteMissingDashboards(cfg.RepoSavePath, dashboards, cfg); err != nil {
-			return fmt.Errorf("failed to delete missing dashboards: %w", err)
+	if cfg.NormalizeDashboards {
+		if err := normalizeDashboards(dashboards, cfg); err != nil {
+			return fmt.Errorf("failed to normalize dashboards: %w", err)
 		}
 	}
 
-	savedCount, err := utils.Retry(ctx, cfg, "save dashboards", func() (int, error) {
-		return saveDashboards(ctx, dashboards, cfg)
+	if cfg.TransformRulesPath != "" {
+		if err := transformDashboards(dashboards, cfg); err != nil {
+			return fmt.Errorf("failed to transform dashboards: %w", err)
+		}
+	}
+
+	if cfg.ApplyOverlays {
+		if err := applyOverlays(dashboards, cfg); err != nil {
+			return fmt.Errorf("failed to apply overlays: %w", err)
+		}
+	}
+
+	toSave := dashboards
+	if cfg.SecretScan {
+		toSave, err = scanSecrets(dashboards, cfg, runReport)
+		if err != nil {
+			return fmt.Errorf("failed to scan dashboards for secrets: %w", err)
+		}
+	}
+	runReport.Dashboards = len(toSave)
+
+	if cfg.Lint {
+		if err := lintDashboards(toSave, cfg, runReport); err != nil {
+			return fmt.Errorf("failed to lint dashboards: %w", err)
+		}
+	}
+
+	if cfg.ValidateQueries {
+		if err := validateQueries(toSave, runReport); err != nil {
+			return fmt.Errorf("failed to validate queries: %w", err)
+		}
+	}
+
+	if cfg.ReportPath != "" {
+		if err := runReport.Write(cfg.ReportPath); err != nil {
+			return fmt.Errorf("failed to write run report: %w", err)
+		}
+		logger.Log.Info().Str("path", cfg.ReportPath).Msg("Wrote run report")
+	}
+
+	if cfg.FailOnInvalidQuery && len(runReport.Queries) > 0 {
+		return fmt.Errorf("found %d invalid queries", len(runReport.Queries))
+	}
+
+	blockPush := false
+	if cfg.Lint && lint.HasErrors(runReport.Lint) {
+		switch cfg.LintBlock {
+		case config.LintBlockCommit:
+			return fmt.Errorf("lint errors found, refusing to commit")
+		case config.LintBlockPush:
+			logger.Log.Warn().Msg("Lint errors found, changes will be committed but not pushed")
+			blockPush = true
+		}
+	}
+
+	summary, err := computeChanges(cfg, dashboards, toSave)
+	if err != nil {
+		return err
+	}
+
+	if !useGit {
+		return exportToFilesystem(ctx, dashboards, toSave, summary, cfg)
+	}
+
+	message, err := renderCommitMessage(cfg, summary, branchName)
+	if err != nil {
+		return err
+	}
+
+	commitOpts, err := commitOptions(cfg)
+	if err != nil {
+		return err
+	}
+
+	if _, err := writeExport(ctx, dashboards, toSave, cfg); err != nil {
+		return err
+	}
+
+	changedFiles, err := gitClient.ChangedFiles(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get changed files: %w", err)
+	}
+	logger.Log.Debug().Strs("files", changedFiles).Msg("Changed files")
+
+	if cfg.BranchMode == config.BranchModeSync && len(changedFiles) > 0 {
+		unchanged, err := syncBranchUnchanged(ctx, gitClient, branchName, message, commitOpts)
+		if err != nil {
+			return err
+		}
+		if unchanged {
+			logger.Log.Info().Str("branch", branchName).Msg("Sync branch already contains this export")
+			changedFiles = nil
+		}
+	}
+
+	if cfg.ChangesOutputPath != "" {
+		if err := writeChangesOutput(cfg.ChangesOutputPath, changedFiles); err != nil {
+			return fmt.Errorf("failed to write changes output: %w", err)
+		}
+	}
+
+	if len(changedFiles) == 0 {
+		logger.Log.Info().Msg("No changes to commit")
+		if cfg.NoChangesExitCode != 0 {
+			return &exitError{code: int(cfg.NoChangesExitCode), reason: "no changes"}
+		}
+		return nil
+	}
+
+	_, err = utils.Retry(ctx, cfg, "commit and push changes", func() (interface{}, error) {
+		if cfg.BranchMode == config.BranchModeBase {
+			return nil, commitAndPushToBase(ctx, gitClient, cfg, dashboards, toSave, message, commitOpts, blockPush, runReport)
+		}
+		return nil, commitAndPushChanges(ctx, gitClient, cfg, branchName, message, commitOpts, blockPush)
 	})
 	if err != nil {
 		return err
 	}
-	logger.Log.Debug().Int("count", savedCount).Msg("Saved dashboards")
+	logger.Log.Info().Int("files", len(changedFiles)).Str("branch", branchName).Msg("Committed and pushed dashboard changes")
 
-	if savedCount > 0 {
-		_, err = utils.Retry(ctx, cfg, "commit and push changes", func() (interface{}, error) {
-			return nil, commitAndPushChanges(ctx, gitClient, cfg, branchName)
+	pushed := !cfg.DryRun && !blockPush
+	runReport.Pushes = append(runReport.Pushes, report.PushResult{Remote: "origin", URL: cfg.RemoteURL(), Branch: branchName, Pushed: pushed})
+
+	var tagName string
+	if cfg.ExportTag && pushed {
+		tagName, err = tagExport(ctx, gitClient, grafanaClient, cfg, branchName, len(toSave), commitOpts)
+		if err != nil {
+			return fmt.Errorf("failed to tag export: %w", err)
+		}
+		runReport.Tag = tagName
+	}
+
+	var mirrorErr error
+	if pushed && len(mirrors) > 0 {
+		mirrorErr = pushMirrors(ctx, gitClient, cfg, mirrors, branchName, tagName, runReport)
+	}
+	if cfg.ReportPath != "" {
+		if err := runReport.Write(cfg.ReportPath); err != nil {
+			return fmt.Errorf("failed to write run report: %w", err)
+		}
+	}
+	if mirrorErr != nil {
+		return mirrorErr
+	}
+
+	if cfg.PRProvider != "" && pushed {
+		_, err = utils.Retry(ctx, cfg, "open pull request", func() (interface{}, error) {
+			return nil, openPullRequest(ctx, cfg, branchName, summary)
 		})
 		if err != nil {
 			return err
 		}
-		logger.Log.Info().Int("count", savedCount).Str("branch", branchName).Msg("Committed and pushed dashboard changes")
+	}
+
+	return nil
+}
+
+// exportToFilesystem writes the export to REPO_SAVE_PATH without any Git handling. Changes are
+// taken from the dashboard summary since there is no worktree to compare against.
+func exportToFilesystem(ctx context.Context, dashboards, toSave []grafana.Dashboard, summary *changes.Summary, cfg *config.Config) error {
+	var changedFiles []string
+	for _, list := range [][]changes.Dashboard{summary.Added, summary.Modified, summary.Moved, summary.Deleted} {
+		for _, d := range list {
+			changedFiles = append(changedFiles, d.Path)
+		}
+	}
+
+	if cfg.ChangesOutputPath != "" {
+		if err := writeChangesOutput(cfg.ChangesOutputPath, changedFiles); err != nil {
+			return fmt.Errorf("failed to write changes output: %w", err)
+		}
+	}
+
+	if cfg.DryRun {
+		logger.Log.Info().Int("changes", len(changedFiles)).Str("path", cfg.RepoSavePath).Msg("Dry run mode: Dashboards not written")
 	} else {
-		logger.Log.Info().Msg("No changes to commit")
+		if err := os.MkdirAll(cfg.RepoSavePath, 0755); err != nil {
+			return fmt.Errorf("failed to create export directory: %w", err)
+		}
+		savedCount, err := writeExport(ctx, dashboards, toSave, cfg)
+		if err != nil {
+			return err
+		}
+		logger.Log.Info().Int("count", savedCount).Str("path", cfg.RepoSavePath).Msg("Wrote dashboards to filesystem")
 	}
 
+	if len(changedFiles) == 0 {
+		logger.Log.Info().Msg("No dashboard changes")
+		if cfg.NoChangesExitCode != 0 {
+			return &exitError{code: int(cfg.NoChangesExitCode), reason: "no changes"}
+		}
+	}
 	return nil
 }
 
+// exitError ends the run successfully but with a non-zero exit code.
+type exitError struct {
+	code   int
+	reason string
+}
+
+func (e *exitError) Error() string {
+	return fmt.Sprintf("exiting with code %d: %s", e.code, e.reason)
+}
+
+// writeChangesOutput appends key=value lines to path, e.g. $GITHUB_OUTPUT.
+func writeChangesOutput(path string, changedFiles []string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(f, "changed=%t\nchanged_files=%d\n", len(changedFiles) > 0, len(changedFiles)); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeExport(ctx context.Context, dashboards, toSave []grafana.Dashboard, cfg *config.Config) (int, error) {
+	if cfg.DeleteMissing {
+		if err := deleteMissingDashboards(cfg.RepoSavePath, dashboards, cfg); err != nil {
+			return 0, fmt.Errorf("failed to delete missing dashboards: %w", err)
+		}
+	}
+
+	savedCount, err := utils.Retry(ctx, cfg, "save dashboards", func() (int, error) {
+		return saveDashboards(ctx, toSave, cfg)
+	})
+	if err != nil {
+		return 0, err
+	}
+	logger.Log.Debug().Int("count", savedCount).Msg("Saved dashboards")
+
+	if cfg.InventoryPath != "" {
+		if err := writeInventory(toSave, cfg); err != nil {
+			return 0, fmt.Errorf("failed to write query inventory: %w", err)
+		}
+	}
+
+	return savedCount, nil
+}
+
 func deleteMissingDashboards(repoSavePath string, fetchedDashboards []grafana.Dashboard, cfg *config.Config) error {
 	existingFiles := make(map[string]bool)
 	fetchedPaths := make(map[string]bool)
@@ -105,7 +378,7 @@ func deleteMissingDashboards(repoSavePath string, fetchedDashboards []grafana.Da
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() && strings.HasSuffix(info.Name(), ".json") {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), ".json") && !overlay.IsPatchFile(info.Name()) {
 			relPath, err := filepath.Rel(repoSavePath, path)
 			if err != nil {
 				return fmt.Errorf("failed to get relative path: %w", err)
@@ -189,8 +462,155 @@ func isDirEmpty(path string) (bool, error) {
 	return false, err
 }
 
-func setupGitClient(cfg *config.Config) (*git.Client, error) {
-	return git.New(cfg.RepoClonePath, cfg.SSHURL, cfg.SSHKey, cfg.SshKeyPassword, cfg.SshKnownHostsPath, cfg.SshAcceptUnknownHosts)
+func setupGitClient(ctx context.Context, cfg *config.Config) (*git.Client, error) {
+	return git.New(ctx, cfg.RepoClonePath, cfg.RemoteURL(), git.AuthOptions{
+		SSHKeyPath:         cfg.SSHKey,
+		SSHKey:             cfg.SSHPrivateKey,
+		SSHKeyPassword:     cfg.SshKeyPassword,
+		SSHCertPath:        cfg.SSHCertPath,
+		UseSSHAgent:        cfg.SSHUseAgent,
+		KnownHostsPath:     cfg.SshKnownHostsPath,
+		AcceptUnknownHosts: cfg.SshAcceptUnknownHosts,
+		Username:           cfg.GitUsername,
+		Token:              cfg.GitToken,
+	}, git.CloneOptions{
+		BaseBranch:   cfg.BaseBranch,
+		Cleanup:      cfg.RepoCleanup,
+		Depth:        int(cfg.CloneDepth),
+		SingleBranch: cfg.CloneSingleBranch,
+	})
+}
+
+// setupMirrors adds a remote for every mirror. A mirror that cannot be set up is skipped and
+// recorded as a failed push in runReport, unless it is required.
+func setupMirrors(gitClient *git.Client, cfg *config.Config, runReport *report.Report) ([]mirror.Mirror, error) {
+	mirrors, err := mirror.Load(cfg.MirrorsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var ready []mirror.Mirror
+	for _, m := range mirrors {
+		if err := gitClient.AddRemote(m.Name, m.URL, m.AuthOptions()); err != nil {
+			if m.Required {
+				return nil, err
+			}
+			logger.Log.Warn().Err(err).Str("mirror", m.Name).Msg("Failed to set up mirror, skipping it")
+			runReport.Pushes = append(runReport.Pushes, report.PushResult{Remote: m.Name, URL: m.URL, Error: err.Error()})
+			continue
+		}
+		ready = append(ready, m)
+	}
+	logger.Log.Debug().Int("count", len(ready)).Msg("Configured mirrors")
+	return ready, nil
+}
+
+// pushMirrors pushes branchName, and tagName if set, to every mirror and records the outcome in
+// runReport. Only failures of required mirrors fail the run.
+func pushMirrors(ctx context.Context, gitClient *git.Client, cfg *config.Config, mirrors []mirror.Mirror, branchName, tagName string, runReport *report.Report) error {
+	var failed []string
+	for _, m := range mirrors {
+		// The sync branch is rewritten on every run, so mirrors can only follow it by force.
+		force := m.Force || cfg.BranchMode == config.BranchModeSync
+		_, err := utils.Retry(ctx, cfg, "push to mirror "+m.Name, func() (interface{}, error) {
+			if err := gitClient.PushRemote(ctx, m.Name, branchName, force); err != nil {
+				return nil, err
+			}
+			if tagName != "" {
+				return nil, gitClient.PushTag(ctx, m.Name, tagName)
+			}
+			return nil, nil
+		})
+
+		result := report.PushResult{Remote: m.Name, URL: m.URL, Branch: branchName, Pushed: err == nil}
+		if err != nil {
+			result.Error = err.Error()
+			logger.Log.Warn().Err(err).Str("mirror", m.Name).Bool("required", m.Required).Msg("Failed to push to mirror")
+			if m.Required {
+				failed = append(failed, m.Name)
+			}
+		} else {
+			logger.Log.Info().Str("mirror", m.Name).Str("branch", branchName).Msg("Pushed to mirror")
+		}
+		runReport.Pushes = append(runReport.Pushes, result)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to push to required mirrors: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// tagExport creates an annotated tag for the pushed export commit, pushes it to origin and prunes
+// tags older than EXPORT_TAG_RETENTION_DAYS.
+func tagExport(ctx context.Context, gitClient *git.Client, grafanaClient *grafana.Client, cfg *config.Config, branchName string, dashboardCount int, commitOpts git.CommitOptions) (string, error) {
+	tmpl, err := tagging.Parse(cfg.ExportTagTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	version, err := grafanaClient.Version(ctx)
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to get Grafana version for the export tag")
+	}
+	data := tagging.NewData(time.Now(), branchName, cfg.RunID, cfg.GrafanaURL, version, dashboardCount)
+	tagName, err := tmpl.Name(data)
+	if err != nil {
+		return "", err
+	}
+
+	if err := gitClient.CreateTag(tagName, tagging.Message(data), commitOpts); err != nil {
+		return "", err
+	}
+	_, err = utils.Retry(ctx, cfg, "push tag", func() (interface{}, error) {
+		return nil, gitClient.PushTag(ctx, "origin", tagName)
+	})
+	if err != nil {
+		return "", err
+	}
+	logger.Log.Info().Str("tag", tagName).Msg("Tagged export")
+
+	if cfg.ExportTagRetentionDays > 0 {
+		if err := pruneTags(ctx, gitClient, cfg, tmpl.Prefix(), tagName); err != nil {
+			logger.Log.Warn().Err(err).Msg("Failed to prune export tags")
+		}
+	}
+	return tagName, nil
+}
+
+func pruneTags(ctx context.Context, gitClient *git.Client, cfg *config.Config, prefix, keep string) error {
+	tags, err := utils.Retry(ctx, cfg, "list remote tags", func() ([]git.RemoteTag, error) {
+		return gitClient.ListRemoteTags(ctx, prefix)
+	})
+	if err != nil {
+		return err
+	}
+
+	expired := tagging.Expired(tags, time.Duration(cfg.ExportTagRetentionDays)*24*time.Hour, time.Now(), keep)
+	if len(expired) == 0 {
+		return nil
+	}
+	_, err = utils.Retry(ctx, cfg, "delete remote tags", func() (interface{}, error) {
+		return nil, gitClient.DeleteRemoteTags(ctx, expired)
+	})
+	if err != nil {
+		return err
+	}
+	logger.Log.Info().Strs("tags", expired).Msg("Pruned expired export tags")
+	return nil
+}
+
+func prepareBranch(ctx context.Context, gitClient *git.Client, cfg *config.Config) (string, error) {
+	switch cfg.BranchMode {
+	case config.BranchModeBase:
+		logger.Log.Debug().Str("baseBranch", cfg.BaseBranch).Msg("Committing directly to base branch")
+		return cfg.BaseBranch, gitClient.CheckoutBranch(ctx, cfg.BaseBranch)
+	case config.BranchModeSync:
+		logger.Log.Debug().Str("baseBranch", cfg.BaseBranch).Str("syncBranch", cfg.SyncBranch).Msg("Resetting sync branch")
+		return cfg.SyncBranch, gitClient.CheckoutSyncBranch(ctx, cfg.BaseBranch, cfg.SyncBranch)
+	default:
+		return createNewBranch(ctx, gitClient, cfg)
+	}
 }
 
 func createNewBranch(ctx context.Context, gitClient *git.Client, cfg *config.Config) (string, error) {
@@ -199,11 +619,288 @@ func createNewBranch(ctx context.Context, gitClient *git.Client, cfg *config.Con
 	return gitClient.CheckoutNewBranch(ctx, cfg.BaseBranch, branchName)
 }
 
+func cleanupBranches(ctx context.Context, gitClient *git.Client, cfg *config.Config, branchName string) error {
+	branches, err := utils.Retry(ctx, cfg, "list remote branches", func() ([]git.RemoteBranch, error) {
+		return gitClient.ListRemoteBranches(ctx, cfg.BranchPrefix, cfg.BaseBranch, cfg.BranchCleanupMerged)
+	})
+	if err != nil {
+		return err
+	}
+
+	candidates := cleanup.Select(branches, cleanup.Options{
+		Prefix:    cfg.BranchPrefix,
+		Retention: time.Duration(cfg.BranchRetentionDays) * 24 * time.Hour,
+		Merged:    cfg.BranchCleanupMerged,
+		Max:       int(cfg.BranchCleanupMax),
+		Keep:      []string{cfg.BaseBranch, cfg.SyncBranch, branchName},
+	}, time.Now())
+
+	names := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		logger.Log.Info().Str("branch", c.Branch).Str("reason", c.Reason).Bool("dryRun", cfg.DryRun || cfg.BranchCleanupDryRun).Msg("Stale branch")
+		names = append(names, c.Branch)
+	}
+	if len(names) == 0 || cfg.DryRun || cfg.BranchCleanupDryRun {
+		return nil
+	}
+
+	_, err = utils.Retry(ctx, cfg, "delete remote branches", func() (interface{}, error) {
+		return nil, gitClient.DeleteRemoteBranches(ctx, names)
+	})
+	if err != nil {
+		return err
+	}
+	logger.Log.Info().Int("count", len(names)).Msg("Deleted stale branches")
+	return nil
+}
+
 func fetchDashboards(ctx context.Context, grafanaClient *grafana.Client) ([]grafana.Dashboard, error) {
 	logger.Log.Debug().Msg("Fetching dashboards from Grafana")
 	return grafanaClient.ListAndExportDashboards(ctx)
 }
 
+func normalizeDashboards(dashboards []grafana.Dashboard, cfg *config.Config) error {
+	logger.Log.Debug().
+		Bool("resetTemplating", cfg.NormalizeResetTemplating).
+		Str("timeFrom", cfg.NormalizeTimeFrom).
+		Str("timeTo", cfg.NormalizeTimeTo).
+		Bool("stripRefresh", cfg.NormalizeStripRefresh).
+		Bool("expandRows", cfg.NormalizeExpandRows).
+		Msg("Normalizing dashboards")
+
+	opts := normalize.Options{
+		ResetTemplating: cfg.NormalizeResetTemplating,
+		TimeFrom:        cfg.NormalizeTimeFrom,
+		TimeTo:          cfg.NormalizeTimeTo,
+		StripRefresh:    cfg.NormalizeStripRefresh,
+		ExpandRows:      cfg.NormalizeExpandRows,
+	}
+
+	for i := range dashboards {
+		data, err := dashboards[i].JSONMap()
+		if err != nil {
+			return fmt.Errorf("failed to read dashboard %s: %w", dashboards[i].UID, err)
+		}
+		normalize.Dashboard(data, opts)
+	}
+	return nil
+}
+
+func transformDashboards(dashboards []grafana.Dashboard, cfg *config.Config) error {
+	pipeline, err := transform.Load(cfg.TransformRulesPath)
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Int("ruleCount", pipeline.Len()).Msg("Transforming dashboards")
+
+	for i := range dashboards {
+		data, err := dashboards[i].JSONMap()
+		if err != nil {
+			return fmt.Errorf("failed to read dashboard %s: %w", dashboards[i].UID, err)
+		}
+		if changes := pipeline.Apply(data, dashboards[i].FolderTitle); changes > 0 {
+			logger.Log.Debug().Str("dashboardUID", dashboards[i].UID).Int("changes", changes).Msg("Dashboard transformed")
+		}
+	}
+	return nil
+}
+
+// applyOverlays applies the overlay of every dashboard, wherever it is stored below
+// REPO_SAVE_PATH, and warns about overlays left behind for dashboards that no longer exist.
+func applyOverlays(dashboards []grafana.Dashboard, cfg *config.Config) error {
+	logger.Log.Debug().Str("savePath", cfg.RepoSavePath).Msg("Applying dashboard overlays")
+
+	overlays, err := overlay.Find(cfg.RepoSavePath)
+	if err != nil {
+		return err
+	}
+
+	for i := range dashboards {
+		patchPath, ok := overlays[dashboards[i].UID]
+		if !ok {
+			continue
+		}
+		delete(overlays, dashboards[i].UID)
+
+		patch, err := overlay.Load(patchPath)
+		if err != nil {
+			return err
+		}
+
+		if expected := overlay.PathFor(grafana.GetDashboardPath(cfg.RepoSavePath, dashboards[i], cfg.IgnoreFolderStructure)); patchPath != expected {
+			logger.Log.Warn().
+				Str("dashboardUID", dashboards[i].UID).
+				Str("patch", patchPath).
+				Str("expected", expected).
+				Msg("Overlay is not next to its dashboard")
+		}
+
+		data, err := dashboards[i].JSONMap()
+		if err != nil {
+			return fmt.Errorf("failed to read dashboard %s: %w", dashboards[i].UID, err)
+		}
+
+		patched, err := patch.Apply(data)
+		if err != nil {
+			return fmt.Errorf("overlay %s no longer applies cleanly to dashboard %s: %w", patchPath, dashboards[i].UID, err)
+		}
+		dashboards[i].Data = patched
+
+		logger.Log.Info().
+			Str("dashboardUID", dashboards[i].UID).
+			Str("patch", patchPath).
+			Int("operations", len(patch)).
+			Msg("Applied dashboard overlay")
+	}
+
+	orphaned := make([]string, 0, len(overlays))
+	for _, patchPath := range overlays {
+		orphaned = append(orphaned, patchPath)
+	}
+	sort.Strings(orphaned)
+	for _, patchPath := range orphaned {
+		logger.Log.Warn().Str("patch", patchPath).Msg("Overlay matches no exported dashboard")
+	}
+	return nil
+}
+
+func scanSecrets(dashboards []grafana.Dashboard, cfg *config.Config, runReport *report.Report) ([]grafana.Dashboard, error) {
+	logger.Log.Debug().Str("action", cfg.SecretScanAction).Msg("Scanning dashboards for secrets")
+
+	var extraPatterns []string
+	if cfg.SecretPatternsPath != "" {
+		var err error
+		extraPatterns, err = secrets.LoadPatterns(cfg.SecretPatternsPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	scanner, err := secrets.New(extraPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	var clean []grafana.Dashboard
+	total := 0
+	for i := range dashboards {
+		data, err := dashboards[i].JSONMap()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dashboard %s: %w", dashboards[i].UID, err)
+		}
+
+		findings := scanner.Scan(data, cfg.SecretScanAction == secrets.ActionRedact)
+		for _, finding := range findings {
+			finding.DashboardUID = dashboards[i].UID
+			runReport.Secrets = append(runReport.Secrets, finding)
+			logger.Log.Warn().
+				Str("dashboardUID", dashboards[i].UID).
+				Str("path", finding.Path).
+				Str("rule", finding.Rule).
+				Str("action", cfg.SecretScanAction).
+				Msg("Secret found in dashboard")
+		}
+		total += len(findings)
+
+		if len(findings) > 0 && cfg.SecretScanAction == secrets.ActionSkip {
+			logger.Log.Warn().Str("dashboardUID", dashboards[i].UID).Msg("Skipping dashboard containing secrets")
+			continue
+		}
+		clean = append(clean, dashboards[i])
+	}
+
+	if total > 0 && cfg.SecretScanAction == secrets.ActionFail {
+		if cfg.ReportPath != "" {
+			runReport.Dashboards = len(clean)
+			if err := runReport.Write(cfg.ReportPath); err != nil {
+				return nil, fmt.Errorf("failed to write run report: %w", err)
+			}
+		}
+		return nil, fmt.Errorf("found %d secrets in dashboards", total)
+	}
+	return clean, nil
+}
+
+func lintDashboards(dashboards []grafana.Dashboard, cfg *config.Config, runReport *report.Report) error {
+	overrides, err := lint.ParseSeverities(cfg.LintSeverities)
+	if err != nil {
+		return err
+	}
+	linter := lint.New(overrides)
+
+	for i := range dashboards {
+		data, err := dashboards[i].JSONMap()
+		if err != nil {
+			return fmt.Errorf("failed to read dashboard %s: %w", dashboards[i].UID, err)
+		}
+
+		for _, v := range linter.Lint(dashboards[i].UID, data) {
+			var event *zerolog.Event
+			switch v.Severity {
+			case lint.SeverityError:
+				event = logger.Log.Error()
+			case lint.SeverityWarning:
+				event = logger.Log.Warn()
+			default:
+				event = logger.Log.Info()
+			}
+			event.
+				Str("dashboardUID", v.DashboardUID).
+				Str("rule", v.Rule).
+				Str("path", v.Path).
+				Msg(v.Message)
+			runReport.Lint = append(runReport.Lint, v)
+		}
+	}
+
+	logger.Log.Info().Int("violations", len(runReport.Lint)).Msg("Linted dashboards")
+	return nil
+}
+
+func validateQueries(dashboards []grafana.Dashboard, runReport *report.Report) error {
+	logger.Log.Debug().Int("dashboardCount", len(dashboards)).Msg("Validating dashboard queries")
+
+	for i := range dashboards {
+		data, err := dashboards[i].JSONMap()
+		if err != nil {
+			return fmt.Errorf("failed to read dashboard %s: %w", dashboards[i].UID, err)
+		}
+
+		for _, issue := range query.ValidateDashboard(dashboards[i].UID, data) {
+			logger.Log.Warn().
+				Str("dashboardUID", issue.DashboardUID).
+				Str("panel", issue.PanelTitle).
+				Str("path", issue.Path).
+				Str("language", issue.Language).
+				Str("error", issue.Message).
+				Msg("Invalid query")
+			runReport.Queries = append(runReport.Queries, issue)
+		}
+	}
+
+	logger.Log.Info().Int("invalidQueries", len(runReport.Queries)).Msg("Validated dashboard queries")
+	return nil
+}
+
+func writeInventory(dashboards []grafana.Dashboard, cfg *config.Config) error {
+	logger.Log.Debug().Str("path", cfg.InventoryPath).Msg("Building query inventory")
+
+	inv, err := inventory.Build(dashboards)
+	if err != nil {
+		return err
+	}
+	if err := inv.Write(cfg.InventoryPath); err != nil {
+		return err
+	}
+
+	logger.Log.Info().
+		Int("metrics", len(inv.Metrics)).
+		Int("lokiLabels", len(inv.LokiLabels)).
+		Str("path", cfg.InventoryPath).
+		Msg("Wrote query inventory")
+	return nil
+}
+
 func saveDashboards(ctx context.Context, dashboards []grafana.Dashboard, cfg *config.Config) (int, error) {
 	logger.Log.Debug().
 		Int("dashboardCount", len(dashboards)).
@@ -261,15 +958,44 @@ func saveDashboard(dashboard grafana.Dashboard, filePath string, cfg *config.Con
 	return nil
 }
 
-func commitAndPushChanges(ctx context.Context, gitClient *git.Client, cfg *config.Config, branchName string) error {
-	logger.Log.Debug().Str("branch", branchName).Msg("Committing changes")
-	if err := gitClient.CommitAll(ctx, cfg.SSHUser, cfg.SSHEmail); err != nil {
-		return fmt.Errorf("failed to commit changes: %w", err)
+// syncBranchUnchanged commits the export and reports whether the result matches the remote sync
+// branch. The sync branch is rebuilt from the base branch on every run, so comparing against the base
+// alone would push a new commit, and update the pull request, even when Grafana did not change.
+func syncBranchUnchanged(ctx context.Context, gitClient *git.Client, branchName, message string, commitOpts git.CommitOptions) (bool, error) {
+	if err := gitClient.CommitAll(ctx, message, commitOpts); err != nil {
+		return false, fmt.Errorf("failed to commit changes: %w", err)
+	}
+	unchanged, err := gitClient.MatchesRemote(branchName)
+	if err != nil {
+		return false, fmt.Errorf("failed to compare with remote sync branch: %w", err)
 	}
+	return unchanged, nil
+}
 
-	if !cfg.DryRun {
+func commitAndPushChanges(ctx context.Context, gitClient *git.Client, cfg *config.Config, branchName, message string, commitOpts git.CommitOptions, blockPush bool) error {
+	changedFiles, err := gitClient.ChangedFiles(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get changed files: %w", err)
+	}
+	if len(changedFiles) == 0 {
+		// A previous attempt already committed, or the export matches the updated base branch.
+		logger.Log.Debug().Str("branch", branchName).Msg("Nothing left to commit")
+	} else {
+		logger.Log.Debug().Str("branch", branchName).Msg("Committing changes")
+		if err := gitClient.CommitAll(ctx, message, commitOpts); err != nil {
+			return fmt.Errorf("failed to commit changes: %w", err)
+		}
+	}
+
+	if blockPush {
+		logger.Log.Info().Msg("Push blocked by lint errors: Changes committed but not pushed")
+	} else if !cfg.DryRun {
 		logger.Log.Debug().Str("branch", branchName).Msg("Pushing changes")
-		if err := gitClient.Push(ctx, branchName); err != nil {
+		push := gitClient.Push
+		if cfg.BranchMode == config.BranchModeSync {
+			push = gitClient.PushWithLease
+		}
+		if err := push(ctx, branchName); err != nil {
 			return fmt.Errorf("failed to push changes: %w", err)
 		}
 	} else {
@@ -279,6 +1005,253 @@ func commitAndPushChanges(ctx context.Context, gitClient *git.Client, cfg *confi
 	return nil
 }
 
+func commitOptions(cfg *config.Config) (git.CommitOptions, error) {
+	opts := git.CommitOptions{
+		AuthorName:     cfg.GitAuthorName,
+		AuthorEmail:    cfg.GitAuthorEmail,
+		CommitterName:  cfg.GitCommitterName,
+		CommitterEmail: cfg.GitCommitterEmail,
+	}
+	if opts.AuthorName == "" {
+		opts.AuthorName = cfg.SSHUser
+	}
+	if opts.AuthorEmail == "" {
+		opts.AuthorEmail = cfg.SSHEmail
+	}
+
+	if cfg.SigningFormat != "" {
+		signer, err := signing.Load(cfg.SigningFormat, cfg.SigningKeyPath, cfg.SigningKey, cfg.SigningKeyPassphrase)
+		if err != nil {
+			return opts, fmt.Errorf("failed to load signing key: %w", err)
+		}
+		opts.Signer = signer
+		logger.Log.Debug().Str("format", cfg.SigningFormat).Msg("Signing commits")
+	}
+	return opts, nil
+}
+
+// computeChanges compares the export with the dashboards currently in REPO_SAVE_PATH, so it has
+// to run before the export is written.
+func computeChanges(cfg *config.Config, dashboards, toSave []grafana.Dashboard) (*changes.Summary, error) {
+	summary, err := changes.Compute(cfg.RepoSavePath, toSave, dashboards, changes.Options{
+		GrafanaURL:            cfg.GrafanaURL,
+		IgnoreFolderStructure: cfg.IgnoreFolderStructure,
+		DeleteMissing:         cfg.DeleteMissing,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute dashboard changes: %w", err)
+	}
+	logger.Log.Info().
+		Int("added", len(summary.Added)).
+		Int("modified", len(summary.Modified)).
+		Int("moved", len(summary.Moved)).
+		Int("deleted", len(summary.Deleted)).
+		Msg("Computed dashboard changes")
+	return summary, nil
+}
+
+func renderCommitMessage(cfg *config.Config, summary *changes.Summary, branchName string) (string, error) {
+	var tmpl *commitmsg.Template
+	var err error
+	if cfg.CommitMessageTemplatePath != "" {
+		tmpl, err = commitmsg.Load(cfg.CommitMessageTemplatePath)
+	} else {
+		tmpl, err = commitmsg.Parse(cfg.CommitMessageTemplate)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	message, err := tmpl.Render(commitmsg.NewData(summary, cfg.GrafanaURL, branchName, cfg.RunID))
+	if err != nil {
+		return "", err
+	}
+	logger.Log.Debug().Str("runID", cfg.RunID).Str("message", message).Msg("Rendered commit message")
+	return message, nil
+}
+
+func openPullRequest(ctx context.Context, cfg *config.Config, branchName string, summary *changes.Summary) error {
+	provider, err := pullrequest.New(cfg.PRProvider, cfg.RemoteURL(), cfg.PRAPIURL, cfg.PRToken)
+	if err != nil {
+		return err
+	}
+
+	body := cfg.PRBody
+	if body == "" {
+		body = fmt.Sprintf("Dashboards exported from %s by grafana-db-exporter.", cfg.GrafanaURL)
+	}
+	body += "\n\n" + summary.Markdown()
+
+	// Every run in new branch mode pushes a fresh branch, so the new pull request replaces the
+	// ones opened by earlier runs.
+	var supersedePrefix string
+	if cfg.BranchMode == "" || cfg.BranchMode == config.BranchModeNew {
+		supersedePrefix = cfg.BranchPrefix
+	}
+
+	result, err := provider.Ensure(ctx, pullrequest.Options{
+		SourceBranch:       branchName,
+		TargetBranch:       cfg.BaseBranch,
+		Title:              cfg.PRTitle,
+		Description:        body,
+		Labels:             splitList(cfg.PRLabels),
+		Assignees:          splitList(cfg.PRAssignees),
+		Reviewers:          splitList(cfg.PRReviewers),
+		Draft:              cfg.PRDraft,
+		RemoveSourceBranch: cfg.PRRemoveSourceBranch,
+		AutoMerge:          cfg.PRAutoMerge,
+		SupersedePrefix:    supersedePrefix,
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Log.Info().
+		Str("provider", provider.Name()).
+		Int("id", result.ID).
+		Str("url", result.URL).
+		Bool("created", result.Created).
+		Ints("superseded", result.Superseded).
+		Msg("Pull request ready")
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// commitAndPushToBase commits onto the base branch and, when the push is rejected because the base
+// branch moved, resets to the remote branch, writes the export again and retries.
+func commitAndPushToBase(ctx context.Context, gitClient *git.Client, cfg *config.Config, dashboards, toSave []grafana.Dashboard, message string, commitOpts git.CommitOptions, blockPush bool, runReport *report.Report) error {
+	for attempt := uint(1); ; attempt++ {
+		err := commitAndPushChanges(ctx, gitClient, cfg, cfg.BaseBranch, message, commitOpts, blockPush)
+		if !errors.Is(err, git.ErrNonFastForward) {
+			return err
+		}
+		if attempt > cfg.NumOfRetries {
+			// Replaying the same push cannot succeed, so don't let the caller retry it.
+			return utils.Permanent(err)
+		}
+
+		logger.Log.Warn().
+			Err(err).
+			Str("branch", cfg.BaseBranch).
+			Uint("attempt", attempt).
+			Msg("Base branch moved during export, re-applying export on top of it")
+
+		conflicts, err := gitClient.ConflictingFiles(ctx, cfg.BaseBranch)
+		if err != nil {
+			return fmt.Errorf("failed to check for conflicting changes: %w", err)
+		}
+		if len(conflicts) > 0 {
+			if err := handleConflicts(cfg, conflicts, runReport); err != nil {
+				return utils.Permanent(err)
+			}
+		}
+
+		// The reset discards the export, so a retry by the caller would push a partial or empty commit.
+		if err := gitClient.ResetToRemote(ctx, cfg.BaseBranch); err != nil {
+			return utils.Permanent(fmt.Errorf("failed to update base branch: %w", err))
+		}
+
+		// The commit message describes the changes against the updated base branch.
+		summary, err := computeChanges(cfg, dashboards, toSave)
+		if err != nil {
+			return utils.Permanent(err)
+		}
+		message, err = renderCommitMessage(cfg, summary, cfg.BaseBranch)
+		if err != nil {
+			return utils.Permanent(err)
+		}
+
+		if _, err := writeExport(ctx, dashboards, toSave, cfg); err != nil {
+			return utils.Permanent(err)
+		}
+	}
+}
+
+// handleConflicts reports files that were changed in Git since the export started and would be
+// overwritten by it. Unless BASE_CONFLICT_ACTION is overwrite, the export is aborted.
+func handleConflicts(cfg *config.Config, conflicts []string, runReport *report.Report) error {
+	runReport.Conflicts = conflicts
+	for _, file := range conflicts {
+		logger.Log.Warn().Str("file", file).Msg("File was changed both in Git and in Grafana")
+	}
+
+	if cfg.BaseConflictAction == config.ConflictActionOverwrite {
+		logger.Log.Warn().Int("count", len(conflicts)).Msg("Overwriting changes made in Git with the Grafana export")
+		return nil
+	}
+
+	if cfg.ReportPath != "" {
+		if err := runReport.Write(cfg.ReportPath); err != nil {
+			return fmt.Errorf("failed to write run report: %w", err)
+		}
+	}
+	return fmt.Errorf("%d files were changed both in Git and in Grafana since the export started: %s", len(conflicts), strings.Join(conflicts, ", "))
+}
+
+// validateFeatures checks the settings that only the feature packages can parse,
+// so that mistakes are reported before anything is cloned or fetched.
+func validateFeatures(cfg *config.Config) error {
+	if cfg.SecretScan && !secrets.ValidAction(cfg.SecretScanAction) {
+		return fmt.Errorf("invalid secret scan action: %s", cfg.SecretScanAction)
+	}
+	if cfg.Lint {
+		if _, err := lint.ParseSeverities(cfg.LintSeverities); err != nil {
+			return fmt.Errorf("invalid lint severities: %w", err)
+		}
+	}
+
+	if cfg.OutputMode == config.OutputModeFilesystem {
+		return nil
+	}
+
+	if cfg.SigningFormat != "" {
+		if !signing.ValidFormat(cfg.SigningFormat) {
+			return fmt.Errorf("invalid signing format: %s", cfg.SigningFormat)
+		}
+		if _, err := signing.Load(cfg.SigningFormat, cfg.SigningKeyPath, cfg.SigningKey, cfg.SigningKeyPassphrase); err != nil {
+			return err
+		}
+	}
+
+	if cfg.CommitMessageTemplatePath != "" {
+		if _, err := commitmsg.Load(cfg.CommitMessageTemplatePath); err != nil {
+			return err
+		}
+	} else if _, err := commitmsg.Parse(cfg.CommitMessageTemplate); err != nil {
+		return err
+	}
+
+	if cfg.ExportTag {
+		tmpl, err := tagging.Parse(cfg.ExportTagTemplate)
+		if err != nil {
+			return err
+		}
+		if cfg.ExportTagRetentionDays > 0 && tmpl.Prefix() == "" {
+			return fmt.Errorf("EXPORT_TAG_TEMPLATE must start with a fixed prefix when EXPORT_TAG_RETENTION_DAYS is set")
+		}
+	}
+
+	if cfg.PRProvider != "" && !pullrequest.ValidProvider(cfg.PRProvider) {
+		return fmt.Errorf("invalid pull request provider: %s", cfg.PRProvider)
+	}
+
+	if cfg.MirrorsPath != "" {
+		if _, err := mirror.Load(cfg.MirrorsPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func setupSignalHandler(cancel context.CancelFunc) {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
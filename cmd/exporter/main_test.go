@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"grafana-db-exporter/internal/config"
+	"grafana-db-exporter/internal/git"
+	"grafana-db-exporter/internal/grafana"
+	"grafana-db-exporter/internal/pullrequest"
+	"grafana-db-exporter/internal/report"
+	"grafana-db-exporter/internal/secrets"
+	"grafana-db-exporter/internal/utils"
+)
+
+func TestValidateFeatures(t *testing.T) {
+	tempDir := t.TempDir()
+
+	invalidTemplatePath := filepath.Join(tempDir, "commit-message.tmpl")
+	if err := os.WriteFile(invalidTemplatePath, []byte("{{.Total"), 0600); err != nil {
+		t.Fatalf("Failed to write commit message template: %v", err)
+	}
+
+	invalidMirrorsPath := filepath.Join(tempDir, "mirrors.json")
+	if err := os.WriteFile(invalidMirrorsPath, []byte(`[{"name": "origin", "url": "git@example.com:test/repo.git"}]`), 0600); err != nil {
+		t.Fatalf("Failed to write mirrors: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		wantErr bool
+	}{
+		{
+			name: "Defaults",
+			cfg:  &config.Config{},
+		},
+		{
+			name: "Valid secret scan action",
+			cfg: &config.Config{
+				SecretScan:       true,
+				SecretScanAction: secrets.ActionRedact,
+			},
+		},
+		{
+			name: "Invalid secret scan action",
+			cfg: &config.Config{
+				SecretScan:       true,
+				SecretScanAction: "ignore",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Valid lint severities",
+			cfg: &config.Config{
+				Lint:           true,
+				LintSeverities: "missing-description=error",
+			},
+		},
+		{
+			name: "Invalid lint severities",
+			cfg: &config.Config{
+				Lint:           true,
+				LintSeverities: "missing-description=fatal",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid signing format",
+			cfg: &config.Config{
+				SigningFormat: "x509",
+				SigningKey:    "key",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Unparseable signing key",
+			cfg: &config.Config{
+				SigningFormat: "openpgp",
+				SigningKey:    "not a key",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid commit message template",
+			cfg: &config.Config{
+				CommitMessageTemplate: "{{.Total",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid commit message template file",
+			cfg: &config.Config{
+				CommitMessageTemplatePath: invalidTemplatePath,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Export tag with retention",
+			cfg: &config.Config{
+				ExportTag:              true,
+				ExportTagTemplate:      "export-{{.Date}}",
+				ExportTagRetentionDays: 90,
+			},
+		},
+		{
+			name: "Invalid export tag template",
+			cfg: &config.Config{
+				ExportTag:         true,
+				ExportTagTemplate: "export-{{.Date",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Export tag retention without fixed prefix",
+			cfg: &config.Config{
+				ExportTag:              true,
+				ExportTagTemplate:      "{{.Date}}",
+				ExportTagRetentionDays: 90,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid mirrors",
+			cfg: &config.Config{
+				MirrorsPath: invalidMirrorsPath,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Valid pull request provider",
+			cfg: &config.Config{
+				PRProvider: pullrequest.ProviderGitea,
+			},
+		},
+		{
+			name: "Invalid pull request provider",
+			cfg: &config.Config{
+				PRProvider: "gerrit",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Commit message template ignored without Git",
+			cfg: &config.Config{
+				OutputMode:            config.OutputModeFilesystem,
+				CommitMessageTemplate: "{{.Total",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateFeatures(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateFeatures() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCommitAndPushToBase(t *testing.T) {
+	tests := []struct {
+		name          string
+		template      string
+		wantPermanent bool
+	}{
+		{
+			name:     "Re-applies the export after a non-fast-forward push",
+			template: "Export {{.Total}} dashboards",
+		},
+		{
+			name:          "Errors after the reset are permanent",
+			template:      "Export {{.Missing}}",
+			wantPermanent: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			originPath := initOrigin(t)
+
+			clonePath := filepath.Join(t.TempDir(), "repo")
+			gitClient, err := git.New(ctx, clonePath, originPath, git.AuthOptions{SSHKey: generateSSHKey(t), AcceptUnknownHosts: true}, git.CloneOptions{BaseBranch: "main"})
+			if err != nil {
+				t.Fatalf("git.New() error = %v", err)
+			}
+
+			cfg := &config.Config{
+				RepoSavePath:          filepath.Join(clonePath, "dashboards"),
+				BaseBranch:            "main",
+				BranchMode:            config.BranchModeBase,
+				NumOfRetries:          1,
+				CommitMessageTemplate: tt.template,
+			}
+			dashboards := []grafana.Dashboard{{UID: "dash1", Title: "Dashboard", Data: map[string]interface{}{"uid": "dash1", "title": "Dashboard"}}}
+			if _, err := writeExport(ctx, dashboards, dashboards, cfg); err != nil {
+				t.Fatalf("writeExport() error = %v", err)
+			}
+
+			// Move the base branch after the export was written.
+			other, otherPath := cloneOrigin(t, originPath)
+			commitFile(t, other, otherPath, "other.txt", "other change")
+			if err := other.Push(&gogit.PushOptions{}); err != nil {
+				t.Fatalf("Failed to push from other clone: %v", err)
+			}
+
+			commitOpts := git.CommitOptions{AuthorName: "test", AuthorEmail: "test@example.com"}
+			err = commitAndPushToBase(ctx, gitClient, cfg, dashboards, dashboards, "stale message", commitOpts, false, report.New())
+
+			var permanent *utils.PermanentError
+			if tt.wantPermanent {
+				if !errors.As(err, &permanent) {
+					t.Fatalf("commitAndPushToBase() error = %v, want a permanent error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("commitAndPushToBase() error = %v", err)
+			}
+
+			result, resultPath := cloneOrigin(t, originPath)
+			for _, name := range []string{"other.txt", filepath.Join("dashboards", "dash1.json")} {
+				if _, err := os.Stat(filepath.Join(resultPath, name)); err != nil {
+					t.Errorf("origin is missing %s: %v", name, err)
+				}
+			}
+			head, err := result.Head()
+			if err != nil {
+				t.Fatalf("Failed to get HEAD: %v", err)
+			}
+			commit, err := result.CommitObject(head.Hash())
+			if err != nil {
+				t.Fatalf("Failed to get HEAD commit: %v", err)
+			}
+			if commit.Message != "Export 1 dashboards" {
+				t.Errorf("commit message = %q, want the message rendered after re-applying", commit.Message)
+			}
+		})
+	}
+}
+
+func generateSSHKey(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate SSH key: %v", err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal SSH key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+}
+
+func initOrigin(t *testing.T) string {
+	t.Helper()
+	tempDir := t.TempDir()
+
+	seedPath := filepath.Join(tempDir, "seed")
+	seed, err := gogit.PlainInitWithOptions(seedPath, &gogit.PlainInitOptions{
+		InitOptions: gogit.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName("main")},
+	})
+	if err != nil {
+		t.Fatalf("Failed to init seed repo: %v", err)
+	}
+	commitFile(t, seed, seedPath, "README.md", "dashboards")
+
+	originPath := filepath.Join(tempDir, "origin.git")
+	if _, err := gogit.PlainClone(originPath, true, &gogit.CloneOptions{URL: seedPath}); err != nil {
+		t.Fatalf("Failed to create origin repo: %v", err)
+	}
+	return originPath
+}
+
+func cloneOrigin(t *testing.T, originPath string) (*gogit.Repository, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "clone")
+	repo, err := gogit.PlainClone(path, false, &gogit.CloneOptions{URL: originPath})
+	if err != nil {
+		t.Fatalf("Failed to clone origin: %v", err)
+	}
+	return repo, path
+}
+
+func commitFile(t *testing.T, repo *gogit.Repository, repoPath, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(repoPath, name), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	if _, err := w.Add(name); err != nil {
+		t.Fatalf("Failed to add %s: %v", name, err)
+	}
+	if _, err := w.Commit("Update "+name, &gogit.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	}); err != nil {
+		t.Fatalf("Failed to commit %s: %v", name, err)
+	}
+}
@@ -0,0 +1,199 @@
+package bitbucket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"grafana-db-exporter/internal/forge"
+	"grafana-db-exporter/internal/logger"
+)
+
+type Client struct {
+	project string
+	repo    string
+	api     *forge.Client
+}
+
+type PullRequestOptions struct {
+	FromBranch  string
+	ToBranch    string
+	Title       string
+	Description string
+	Reviewers   []string
+	Draft       bool
+}
+
+type PullRequest struct {
+	ID      int `json:"id"`
+	Version int `json:"version"`
+	FromRef ref `json:"fromRef"`
+	ToRef   ref `json:"toRef"`
+	Links   struct {
+		Self []struct {
+			Href string `json:"href"`
+		} `json:"self"`
+	} `json:"links"`
+}
+
+type ref struct {
+	ID         string `json:"id"`
+	Repository struct {
+		ID int `json:"id"`
+	} `json:"repository"`
+}
+
+func (pr *PullRequest) URL() string {
+	if len(pr.Links.Self) == 0 {
+		return ""
+	}
+	return pr.Links.Self[0].Href
+}
+
+type page struct {
+	Values        []PullRequest `json:"values"`
+	IsLastPage    bool          `json:"isLastPage"`
+	NextPageStart int           `json:"nextPageStart"`
+}
+
+// New creates a Bitbucket Server/Data Center client. repoPath is PROJECT/repo, optionally prefixed
+// with the scm/ segment of HTTP clone URLs.
+func New(apiURL, token, repoPath string) (*Client, error) {
+	if _, err := url.ParseRequestURI(apiURL); err != nil {
+		return nil, fmt.Errorf("invalid Bitbucket API URL: %w", err)
+	}
+
+	project, repo, ok := strings.Cut(strings.TrimPrefix(repoPath, "scm/"), "/")
+	if !ok || project == "" || repo == "" || strings.Contains(repo, "/") {
+		return nil, fmt.Errorf("invalid Bitbucket repository %q, expected PROJECT/repo", repoPath)
+	}
+
+	return &Client{
+		project: project,
+		repo:    repo,
+		api:     forge.NewClient(apiURL, map[string]string{"Authorization": "Bearer " + token}),
+	}, nil
+}
+
+// EnsurePullRequest updates the open pull request from opts.FromBranch into opts.ToBranch, or creates one
+// if none exists. It reports whether a new pull request was created.
+func (c *Client) EnsurePullRequest(ctx context.Context, opts PullRequestOptions) (*PullRequest, bool, error) {
+	existing, err := c.findPullRequest(ctx, opts.FromBranch, opts.ToBranch)
+	if err != nil {
+		return nil, false, err
+	}
+
+	reviewers := make([]map[string]interface{}, 0, len(opts.Reviewers))
+	for _, r := range opts.Reviewers {
+		reviewers = append(reviewers, map[string]interface{}{"user": map[string]string{"name": r}})
+	}
+
+	if existing != nil {
+		logger.Log.Debug().Int("id", existing.ID).Msg("Updating Bitbucket pull request")
+		body := map[string]interface{}{
+			"version":     existing.Version,
+			"title":       opts.Title,
+			"description": opts.Description,
+		}
+		if len(reviewers) > 0 {
+			body["reviewers"] = reviewers
+		}
+		pr := &PullRequest{}
+		err := c.api.Do(ctx, http.MethodPut, c.repoPath(fmt.Sprintf("pull-requests/%d", existing.ID)), body, pr)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to update pull request %d: %w", existing.ID, err)
+		}
+		return pr, false, nil
+	}
+
+	logger.Log.Debug().Str("from", opts.FromBranch).Str("to", opts.ToBranch).Msg("Creating Bitbucket pull request")
+
+	pr := &PullRequest{}
+	err = c.api.Do(ctx, http.MethodPost, c.repoPath("pull-requests"), map[string]interface{}{
+		"title":       opts.Title,
+		"description": opts.Description,
+		"draft":       opts.Draft,
+		"fromRef":     map[string]string{"id": branchRef(opts.FromBranch)},
+		"toRef":       map[string]string{"id": branchRef(opts.ToBranch)},
+		"reviewers":   reviewers,
+	}, pr)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create pull request: %w", err)
+	}
+	return pr, true, nil
+}
+
+func (c *Client) findPullRequest(ctx context.Context, from, to string) (*PullRequest, error) {
+	query := url.Values{}
+	query.Set("state", "OPEN")
+	query.Set("direction", "OUTGOING")
+	query.Set("at", branchRef(from))
+
+	start := 0
+	for {
+		query.Set("start", fmt.Sprint(start))
+		var p page
+		if err := c.api.Do(ctx, http.MethodGet, c.repoPath("pull-requests")+"?"+query.Encode(), nil, &p); err != nil {
+			return nil, fmt.Errorf("failed to list pull requests: %w", err)
+		}
+		for i := range p.Values {
+			if p.Values[i].ToRef.ID == branchRef(to) {
+				return &p.Values[i], nil
+			}
+		}
+		if p.IsLastPage || len(p.Values) == 0 {
+			return nil, nil
+		}
+		start = p.NextPageStart
+	}
+}
+
+// CloseSupersededPullRequests declines the open pull requests into to from branches of this repository
+// that start with prefix, except the one from keep. It returns the IDs of the declined pull requests.
+func (c *Client) CloseSupersededPullRequests(ctx context.Context, prefix, to, keep string) ([]int, error) {
+	query := url.Values{}
+	query.Set("state", "OPEN")
+	query.Set("direction", "INCOMING")
+	query.Set("at", branchRef(to))
+
+	// Collect all pages first, as declining pull requests would shift the later pages.
+	var superseded []PullRequest
+	start := 0
+	for {
+		query.Set("start", fmt.Sprint(start))
+		var p page
+		if err := c.api.Do(ctx, http.MethodGet, c.repoPath("pull-requests")+"?"+query.Encode(), nil, &p); err != nil {
+			return nil, fmt.Errorf("failed to list pull requests: %w", err)
+		}
+		for _, pr := range p.Values {
+			if pr.FromRef.Repository.ID == pr.ToRef.Repository.ID && pr.FromRef.ID != branchRef(keep) && strings.HasPrefix(pr.FromRef.ID, branchRef(prefix)) {
+				superseded = append(superseded, pr)
+			}
+		}
+		if p.IsLastPage || len(p.Values) == 0 {
+			break
+		}
+		start = p.NextPageStart
+	}
+
+	var declined []int
+	for _, pr := range superseded {
+		logger.Log.Debug().Int("id", pr.ID).Msg("Declining superseded Bitbucket pull request")
+		path := fmt.Sprintf("%s?version=%d", c.repoPath(fmt.Sprintf("pull-requests/%d/decline", pr.ID)), pr.Version)
+		if err := c.api.Do(ctx, http.MethodPost, path, map[string]interface{}{}, nil); err != nil {
+			return declined, fmt.Errorf("failed to decline pull request %d: %w", pr.ID, err)
+		}
+		declined = append(declined, pr.ID)
+	}
+	return declined, nil
+}
+
+func branchRef(branch string) string {
+	return "refs/heads/" + branch
+}
+
+func (c *Client) repoPath(path string) string {
+	return fmt.Sprintf("/projects/%s/repos/%s/%s", url.PathEscape(c.project), url.PathEscape(c.repo), path)
+}
@@ -0,0 +1,147 @@
+package bitbucket
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"grafana-db-exporter/internal/forge/forgetest"
+)
+
+const repoPath = "/rest/api/1.0/projects/OPS/repos/dashboards"
+
+func newStubServer(t *testing.T, existing string) *forgetest.Server {
+	t.Helper()
+	return forgetest.NewServer(t, "Authorization", "Bearer test-token", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == repoPath+"/pull-requests":
+			_, _ = w.Write([]byte(existing))
+		case r.Method == http.MethodPost && r.URL.Path == repoPath+"/pull-requests":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":12,"version":0,"links":{"self":[{"href":"https://bitbucket.example.com/projects/OPS/repos/dashboards/pull-requests/12"}]}}`))
+		case r.Method == http.MethodPut && r.URL.Path == repoPath+"/pull-requests/8":
+			_, _ = w.Write([]byte(`{"id":8,"version":3}`))
+		case r.Method == http.MethodPost && r.URL.Path == repoPath+"/pull-requests/8/decline":
+			_, _ = w.Write([]byte(`{"id":8,"version":3}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[{"message":"not found"}]}`))
+		}
+	})
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		repoPath string
+		wantErr  bool
+	}{
+		{name: "SSH path", repoPath: "OPS/dashboards"},
+		{name: "HTTP clone path", repoPath: "scm/OPS/dashboards"},
+		{name: "Missing project", repoPath: "dashboards", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New("https://bitbucket.example.com/rest/api/1.0", "token", tt.repoPath)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_EnsurePullRequest_Create(t *testing.T) {
+	server := newStubServer(t, `{"values":[{"id":3,"version":1,"toRef":{"id":"refs/heads/release"}}],"isLastPage":true}`)
+	client, err := New(server.URL+"/rest/api/1.0", "test-token", "scm/OPS/dashboards")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	pr, created, err := client.EnsurePullRequest(context.Background(), PullRequestOptions{
+		FromBranch: "grafana-db-exporter/sync",
+		ToBranch:   "main",
+		Title:      "Update Grafana dashboards",
+		Reviewers:  []string{"alice"},
+	})
+	if err != nil {
+		t.Fatalf("EnsurePullRequest() error = %v", err)
+	}
+	if !created || pr.ID != 12 || pr.URL() == "" {
+		t.Errorf("EnsurePullRequest() = %+v, created %v, want 12 created", pr, created)
+	}
+
+	list := server.Request(http.MethodGet, repoPath+"/pull-requests")
+	if list == nil || list.Query != "at=refs%2Fheads%2Fgrafana-db-exporter%2Fsync&direction=OUTGOING&start=0&state=OPEN" {
+		t.Errorf("List request = %+v", list)
+	}
+
+	create := server.Request(http.MethodPost, repoPath+"/pull-requests")
+	fromRef, _ := create.Body["fromRef"].(map[string]interface{})
+	reviewers, _ := create.Body["reviewers"].([]interface{})
+	if fromRef["id"] != "refs/heads/grafana-db-exporter/sync" || len(reviewers) != 1 {
+		t.Errorf("Create body = %v", create.Body)
+	}
+}
+
+func TestClient_EnsurePullRequest_Update(t *testing.T) {
+	server := newStubServer(t, `{"values":[{"id":8,"version":2,"toRef":{"id":"refs/heads/main"}}],"isLastPage":true}`)
+	client, err := New(server.URL+"/rest/api/1.0", "test-token", "OPS/dashboards")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	pr, created, err := client.EnsurePullRequest(context.Background(), PullRequestOptions{
+		FromBranch:  "grafana-db-exporter/sync",
+		ToBranch:    "main",
+		Title:       "Update Grafana dashboards",
+		Description: "description",
+		Reviewers:   []string{"alice"},
+	})
+	if err != nil {
+		t.Fatalf("EnsurePullRequest() error = %v", err)
+	}
+	if created || pr.ID != 8 {
+		t.Errorf("EnsurePullRequest() = %+v, created %v, want existing 8", pr, created)
+	}
+
+	update := server.Request(http.MethodPut, repoPath+"/pull-requests/8")
+	if update == nil || update.Body["version"] != float64(2) || update.Body["description"] != "description" {
+		t.Errorf("Update request = %+v", update)
+	}
+	if reviewers, _ := update.Body["reviewers"].([]interface{}); len(reviewers) != 1 {
+		t.Errorf("Update body = %v, want reviewers", update.Body)
+	}
+	if server.Request(http.MethodPost, repoPath+"/pull-requests") != nil {
+		t.Errorf("EnsurePullRequest() created a duplicate pull request")
+	}
+}
+
+func TestClient_CloseSupersededPullRequests(t *testing.T) {
+	server := newStubServer(t, `{"values":[
+		{"id":8,"version":2,"fromRef":{"id":"refs/heads/grafana-db-exporter-20240101000000","repository":{"id":1}},"toRef":{"id":"refs/heads/main","repository":{"id":1}}},
+		{"id":9,"version":0,"fromRef":{"id":"refs/heads/grafana-db-exporter-20240102000000","repository":{"id":1}},"toRef":{"id":"refs/heads/main","repository":{"id":1}}},
+		{"id":10,"version":0,"fromRef":{"id":"refs/heads/grafana-db-exporter-20240101000000","repository":{"id":2}},"toRef":{"id":"refs/heads/main","repository":{"id":1}}},
+		{"id":11,"version":0,"fromRef":{"id":"refs/heads/feature","repository":{"id":1}},"toRef":{"id":"refs/heads/main","repository":{"id":1}}}
+	],"isLastPage":true}`)
+	client, err := New(server.URL+"/rest/api/1.0", "test-token", "OPS/dashboards")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	declined, err := client.CloseSupersededPullRequests(context.Background(), "grafana-db-exporter-", "main", "grafana-db-exporter-20240102000000")
+	if err != nil {
+		t.Fatalf("CloseSupersededPullRequests() error = %v", err)
+	}
+	if len(declined) != 1 || declined[0] != 8 {
+		t.Errorf("CloseSupersededPullRequests() = %v, want [8]", declined)
+	}
+
+	list := server.Request(http.MethodGet, repoPath+"/pull-requests")
+	if list.Query != "at=refs%2Fheads%2Fmain&direction=INCOMING&start=0&state=OPEN" {
+		t.Errorf("List query = %s", list.Query)
+	}
+	if decline := server.Request(http.MethodPost, repoPath+"/pull-requests/8/decline"); decline == nil || decline.Query != "version=2" {
+		t.Errorf("Decline request = %+v", decline)
+	}
+}
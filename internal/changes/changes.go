@@ -0,0 +1,302 @@
+package changes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"grafana-db-exporter/internal/grafana"
+	"grafana-db-exporter/internal/overlay"
+)
+
+const (
+	KindAdded    = "added"
+	KindModified = "modified"
+	KindMoved    = "moved"
+	KindDeleted  = "deleted"
+)
+
+type Options struct {
+	GrafanaURL            string
+	IgnoreFolderStructure bool
+	DeleteMissing         bool
+}
+
+type Dashboard struct {
+	UID            string   `json:"uid"`
+	Title          string   `json:"title"`
+	Kind           string   `json:"kind"`
+	Path           string   `json:"path"`
+	OldPath        string   `json:"oldPath,omitempty"`
+	URL            string   `json:"url,omitempty"`
+	ContentChanged bool     `json:"contentChanged"`
+	PanelsAdded    []string `json:"panelsAdded,omitempty"`
+	PanelsRemoved  []string `json:"panelsRemoved,omitempty"`
+	QueriesChanged []string `json:"queriesChanged,omitempty"`
+}
+
+type Summary struct {
+	Added    []Dashboard `json:"added"`
+	Modified []Dashboard `json:"modified"`
+	Moved    []Dashboard `json:"moved"`
+	Deleted  []Dashboard `json:"deleted"`
+}
+
+type previousFile struct {
+	path string
+	data map[string]interface{}
+}
+
+// Compute diffs the dashboard files currently in savePath against the exported dashboards. It must run
+// before the export is written. fetched is the full list of dashboards in Grafana, used to detect deletions.
+func Compute(savePath string, exported, fetched []grafana.Dashboard, opts Options) (*Summary, error) {
+	previous, err := readPrevious(savePath)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{}
+	for _, d := range exported {
+		path, err := filepath.Rel(savePath, grafana.GetDashboardPath(savePath, d, opts.IgnoreFolderStructure))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get relative path: %w", err)
+		}
+
+		current, err := toMap(d.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dashboard %s: %w", d.UID, err)
+		}
+
+		change := Dashboard{
+			UID:   d.UID,
+			Title: d.Title,
+			Path:  path,
+			URL:   dashboardURL(opts.GrafanaURL, d.UID),
+		}
+
+		old, ok := previous[d.UID]
+		if !ok {
+			change.Kind = KindAdded
+			summary.Added = append(summary.Added, change)
+			continue
+		}
+
+		change.ContentChanged = !reflect.DeepEqual(old.data, current)
+		if change.ContentChanged {
+			change.PanelsAdded, change.PanelsRemoved, change.QueriesChanged = diffPanels(old.data, current)
+		}
+
+		switch {
+		case old.path != path:
+			change.Kind = KindMoved
+			change.OldPath = old.path
+			summary.Moved = append(summary.Moved, change)
+		case change.ContentChanged:
+			change.Kind = KindModified
+			summary.Modified = append(summary.Modified, change)
+		}
+	}
+
+	if opts.DeleteMissing {
+		fetchedUIDs := make(map[string]bool, len(fetched))
+		for _, d := range fetched {
+			fetchedUIDs[d.UID] = true
+		}
+		for uid, old := range previous {
+			if fetchedUIDs[uid] {
+				continue
+			}
+			title, _ := old.data["title"].(string)
+			summary.Deleted = append(summary.Deleted, Dashboard{
+				UID:   uid,
+				Title: title,
+				Kind:  KindDeleted,
+				Path:  old.path,
+			})
+		}
+	}
+
+	for _, list := range [][]Dashboard{summary.Added, summary.Modified, summary.Moved, summary.Deleted} {
+		sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	}
+	return summary, nil
+}
+
+func (s *Summary) Empty() bool {
+	return len(s.Added)+len(s.Modified)+len(s.Moved)+len(s.Deleted) == 0
+}
+
+// Markdown renders the summary as a pull request description section.
+func (s *Summary) Markdown() string {
+	var b strings.Builder
+	b.WriteString("### Dashboard changes\n")
+	if s.Empty() {
+		b.WriteString("\nNo dashboard changes.\n")
+		return b.String()
+	}
+
+	section := func(heading string, list []Dashboard, line func(Dashboard) string) {
+		if len(list) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n**%s (%d)**\n\n", heading, len(list))
+		for _, d := range list {
+			fmt.Fprintf(&b, "- %s\n", line(d))
+			writePanelChanges(&b, d)
+		}
+	}
+
+	section("Added", s.Added, func(d Dashboard) string {
+		return fmt.Sprintf("%s (`%s`)", link(d), d.Path)
+	})
+	section("Modified", s.Modified, func(d Dashboard) string {
+		return fmt.Sprintf("%s (`%s`)", link(d), d.Path)
+	})
+	section("Moved", s.Moved, func(d Dashboard) string {
+		return fmt.Sprintf("%s: `%s` → `%s`", link(d), d.OldPath, d.Path)
+	})
+	section("Deleted", s.Deleted, func(d Dashboard) string {
+		return fmt.Sprintf("%s (`%s`)", title(d), d.Path)
+	})
+	return b.String()
+}
+
+func writePanelChanges(b *strings.Builder, d Dashboard) {
+	for _, item := range []struct {
+		label  string
+		panels []string
+	}{
+		{"Panels added", d.PanelsAdded},
+		{"Panels removed", d.PanelsRemoved},
+		{"Queries changed", d.QueriesChanged},
+	} {
+		if len(item.panels) > 0 {
+			fmt.Fprintf(b, "  - %s: %s\n", item.label, strings.Join(item.panels, ", "))
+		}
+	}
+}
+
+func title(d Dashboard) string {
+	if d.Title == "" {
+		return d.UID
+	}
+	return d.Title
+}
+
+func link(d Dashboard) string {
+	if d.URL == "" {
+		return title(d)
+	}
+	return fmt.Sprintf("[%s](%s)", title(d), d.URL)
+}
+
+func dashboardURL(grafanaURL, uid string) string {
+	if grafanaURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/d/%s", strings.TrimSuffix(grafanaURL, "/"), uid)
+}
+
+func readPrevious(savePath string) (map[string]previousFile, error) {
+	previous := make(map[string]previousFile)
+	err := filepath.Walk(savePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == savePath {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".json") || overlay.IsPatchFile(info.Name()) {
+			return nil
+		}
+
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		var data map[string]interface{}
+		if err := json.Unmarshal(raw, &data); err != nil {
+			// Not a dashboard file, e.g. hand-written JSON next to the export.
+			return nil
+		}
+
+		uid, _ := data["uid"].(string)
+		if uid == "" {
+			uid = strings.TrimSuffix(info.Name(), ".json")
+		}
+		relPath, err := filepath.Rel(savePath, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %w", err)
+		}
+		previous[uid] = previousFile{path: relPath, data: data}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read previous dashboards: %w", err)
+	}
+	return previous, nil
+}
+
+func toMap(data interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+type panelInfo struct {
+	label   string
+	targets interface{}
+}
+
+func panelsByID(data map[string]interface{}) (map[int]panelInfo, []int) {
+	panels := make(map[int]panelInfo)
+	var order []int
+	grafana.ForEachPanel(data, func(_ string, panel map[string]interface{}) {
+		id, ok := grafana.PanelID(panel)
+		if !ok {
+			return
+		}
+		label, _ := panel["title"].(string)
+		if label == "" {
+			label = fmt.Sprintf("panel %d", id)
+		}
+		if _, seen := panels[id]; !seen {
+			order = append(order, id)
+		}
+		panels[id] = panelInfo{label: label, targets: panel["targets"]}
+	})
+	return panels, order
+}
+
+func diffPanels(old, current map[string]interface{}) ([]string, []string, []string) {
+	oldPanels, oldOrder := panelsByID(old)
+	newPanels, newOrder := panelsByID(current)
+
+	var added, removed, queriesChanged []string
+	for _, id := range newOrder {
+		p := newPanels[id]
+		prev, ok := oldPanels[id]
+		switch {
+		case !ok:
+			added = append(added, p.label)
+		case !reflect.DeepEqual(prev.targets, p.targets):
+			queriesChanged = append(queriesChanged, p.label)
+		}
+	}
+	for _, id := range oldOrder {
+		if _, ok := newPanels[id]; !ok {
+			removed = append(removed, oldPanels[id].label)
+		}
+	}
+	return added, removed, queriesChanged
+}
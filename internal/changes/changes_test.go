@@ -0,0 +1,118 @@
+package changes
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"grafana-db-exporter/internal/grafana"
+)
+
+func writeDashboard(t *testing.T, path, raw string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(raw), 0644); err != nil {
+		t.Fatalf("Failed to write dashboard: %v", err)
+	}
+}
+
+func dashboard(t *testing.T, uid, title, folder string, folderID int, raw string) grafana.Dashboard {
+	t.Helper()
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		t.Fatalf("Failed to parse test JSON: %v", err)
+	}
+	return grafana.Dashboard{UID: uid, Title: title, FolderTitle: folder, FolderID: folderID, Data: data}
+}
+
+func TestCompute(t *testing.T) {
+	savePath := t.TempDir()
+	writeDashboard(t, filepath.Join(savePath, "Ops", "same.json"), `{"uid":"same","title":"Same","panels":[]}`)
+	writeDashboard(t, filepath.Join(savePath, "Ops", "edited.json"), `{"uid":"edited","title":"Edited","panels":[
+		{"id":1,"title":"CPU","targets":[{"expr":"up"}]},
+		{"id":2,"title":"Memory","targets":[{"expr":"mem"}]},
+		{"id":3,"title":"Disk"}]}`)
+	writeDashboard(t, filepath.Join(savePath, "Ops", "moved.json"), `{"uid":"moved","title":"Moved","panels":[]}`)
+	writeDashboard(t, filepath.Join(savePath, "gone.json"), `{"uid":"gone","title":"Gone"}`)
+	writeDashboard(t, filepath.Join(savePath, "Ops", "edited.patch.json"), `[]`)
+
+	fetched := []grafana.Dashboard{
+		dashboard(t, "same", "Same", "Ops", 1, `{"uid":"same","title":"Same","panels":[]}`),
+		dashboard(t, "edited", "Edited", "Ops", 1, `{"uid":"edited","title":"Edited","panels":[
+			{"id":1,"title":"CPU","targets":[{"expr":"rate(up[5m])"}]},
+			{"id":2,"title":"Memory","targets":[{"expr":"mem"}]},
+			{"id":4,"title":"Network"}]}`),
+		dashboard(t, "moved", "Moved", "Apps", 2, `{"uid":"moved","title":"Moved","panels":[]}`),
+		dashboard(t, "new", "New", "", 0, `{"uid":"new","title":"New"}`),
+	}
+
+	summary, err := Compute(savePath, fetched, fetched, Options{GrafanaURL: "https://grafana.example.com/", DeleteMissing: true})
+	if err != nil {
+		t.Fatalf("Compute() error = %v", err)
+	}
+
+	if len(summary.Added) != 1 || summary.Added[0].UID != "new" || summary.Added[0].URL != "https://grafana.example.com/d/new" {
+		t.Errorf("Compute() added = %+v", summary.Added)
+	}
+	if len(summary.Moved) != 1 || summary.Moved[0].OldPath != filepath.Join("Ops", "moved.json") || summary.Moved[0].Path != filepath.Join("Apps", "moved.json") || summary.Moved[0].ContentChanged {
+		t.Errorf("Compute() moved = %+v", summary.Moved)
+	}
+	if len(summary.Deleted) != 1 || summary.Deleted[0].UID != "gone" || summary.Deleted[0].Title != "Gone" {
+		t.Errorf("Compute() deleted = %+v", summary.Deleted)
+	}
+	if len(summary.Modified) != 1 {
+		t.Fatalf("Compute() modified = %+v, want one dashboard", summary.Modified)
+	}
+
+	edited := summary.Modified[0]
+	if strings.Join(edited.PanelsAdded, ",") != "Network" ||
+		strings.Join(edited.PanelsRemoved, ",") != "Disk" ||
+		strings.Join(edited.QueriesChanged, ",") != "CPU" {
+		t.Errorf("Compute() panel changes = added %v, removed %v, queries %v", edited.PanelsAdded, edited.PanelsRemoved, edited.QueriesChanged)
+	}
+
+	markdown := summary.Markdown()
+	for _, want := range []string{
+		"**Added (1)**",
+		"- [New](https://grafana.example.com/d/new) (`new.json`)",
+		"  - Queries changed: CPU",
+		"**Moved (1)**",
+		"- Gone (`gone.json`)",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Markdown() does not contain %q:\n%s", want, markdown)
+		}
+	}
+}
+
+func TestCompute_KeepMissing(t *testing.T) {
+	savePath := t.TempDir()
+	writeDashboard(t, filepath.Join(savePath, "gone.json"), `{"uid":"gone","title":"Gone"}`)
+
+	summary, err := Compute(savePath, nil, nil, Options{DeleteMissing: false})
+	if err != nil {
+		t.Fatalf("Compute() error = %v", err)
+	}
+	if !summary.Empty() {
+		t.Errorf("Compute() = %+v, want no changes when missing dashboards are kept", summary)
+	}
+	if !strings.Contains(summary.Markdown(), "No dashboard changes.") {
+		t.Errorf("Markdown() = %s", summary.Markdown())
+	}
+}
+
+func TestCompute_MissingSavePath(t *testing.T) {
+	fetched := []grafana.Dashboard{dashboard(t, "new", "New", "", 0, `{"uid":"new"}`)}
+
+	summary, err := Compute(filepath.Join(t.TempDir(), "missing"), fetched, fetched, Options{DeleteMissing: true})
+	if err != nil {
+		t.Fatalf("Compute() error = %v", err)
+	}
+	if len(summary.Added) != 1 {
+		t.Errorf("Compute() added = %+v, want one dashboard", summary.Added)
+	}
+}
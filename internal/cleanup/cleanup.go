@@ -0,0 +1,80 @@
+package cleanup
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"grafana-db-exporter/internal/git"
+)
+
+const (
+	ReasonMerged  = "merged"
+	ReasonExpired = "expired"
+)
+
+// timestampLayout is the suffix the exporter appends to BRANCH_PREFIX for new branches.
+const timestampLayout = "20060102150405"
+
+type Options struct {
+	Prefix string
+	// Retention deletes branches created longer ago than this; 0 disables age-based cleanup.
+	Retention time.Duration
+	Merged    bool
+	// Max caps the number of branches deleted per run; 0 means no limit.
+	Max  int
+	Keep []string
+}
+
+type Candidate struct {
+	Branch  string
+	Reason  string
+	Created time.Time
+}
+
+// Select returns the branches to delete, oldest first. The creation time is taken from the
+// timestamp in the branch name; branches without one are only deleted once merged.
+func Select(branches []git.RemoteBranch, opts Options, now time.Time) []Candidate {
+	keep := make(map[string]bool, len(opts.Keep))
+	for _, name := range opts.Keep {
+		keep[name] = true
+	}
+
+	var candidates []Candidate
+	for _, b := range branches {
+		if keep[b.Name] || !strings.HasPrefix(b.Name, opts.Prefix) {
+			continue
+		}
+		created, hasTime := BranchTime(b.Name, opts.Prefix)
+		switch {
+		case opts.Merged && b.Merged:
+			candidates = append(candidates, Candidate{Branch: b.Name, Reason: ReasonMerged, Created: created})
+		case opts.Retention > 0 && hasTime && now.Sub(created) > opts.Retention:
+			candidates = append(candidates, Candidate{Branch: b.Name, Reason: ReasonExpired, Created: created})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Created.Equal(candidates[j].Created) {
+			return candidates[i].Branch < candidates[j].Branch
+		}
+		return candidates[i].Created.Before(candidates[j].Created)
+	})
+	if opts.Max > 0 && len(candidates) > opts.Max {
+		candidates = candidates[:opts.Max]
+	}
+	return candidates
+}
+
+// BranchTime parses the creation time from a branch named prefix followed by a timestamp.
+func BranchTime(name, prefix string) (time.Time, bool) {
+	suffix := strings.TrimPrefix(name, prefix)
+	if len(suffix) < len(timestampLayout) {
+		return time.Time{}, false
+	}
+	created, err := time.ParseInLocation(timestampLayout, suffix[:len(timestampLayout)], time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return created, true
+}
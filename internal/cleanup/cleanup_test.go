@@ -0,0 +1,90 @@
+package cleanup
+
+import (
+	"testing"
+	"time"
+
+	"grafana-db-exporter/internal/git"
+)
+
+func TestSelect(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.Local)
+	branches := []git.RemoteBranch{
+		{Name: "grafana-db-exporter-20260101000000"},
+		{Name: "grafana-db-exporter-20261017000000"},
+		{Name: "grafana-db-exporter-20261016000000", Merged: true},
+		{Name: "grafana-db-exporter-20250601000000"},
+		{Name: "grafana-db-exporter-manual", Merged: true},
+		{Name: "grafana-db-exporter-nodate"},
+		{Name: "grafana-db-exporter/sync", Merged: true},
+		{Name: "feature-20200101000000", Merged: true},
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{
+			name: "Retention and merged",
+			opts: Options{Prefix: "grafana-db-exporter-", Retention: 30 * 24 * time.Hour, Merged: true},
+			want: []string{
+				"grafana-db-exporter-manual",
+				"grafana-db-exporter-20250601000000",
+				"grafana-db-exporter-20260101000000",
+				"grafana-db-exporter-20261016000000",
+			},
+		},
+		{
+			name: "Retention only",
+			opts: Options{Prefix: "grafana-db-exporter-", Retention: 30 * 24 * time.Hour},
+			want: []string{"grafana-db-exporter-20250601000000", "grafana-db-exporter-20260101000000"},
+		},
+		{
+			name: "Merged only with cap",
+			opts: Options{Prefix: "grafana-db-exporter-", Merged: true, Max: 1},
+			want: []string{"grafana-db-exporter-manual"},
+		},
+		{
+			name: "Kept branch",
+			opts: Options{Prefix: "grafana-db-exporter", Merged: true, Keep: []string{"grafana-db-exporter/sync", "grafana-db-exporter-manual"}},
+			want: []string{"grafana-db-exporter-20261016000000"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Select(branches, tt.opts, now)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Select() = %+v, want %v", got, tt.want)
+			}
+			for i, c := range got {
+				if c.Branch != tt.want[i] {
+					t.Errorf("Select()[%d] = %s, want %s", i, c.Branch, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBranchTime(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   time.Time
+		wantOK bool
+	}{
+		{name: "grafana-db-exporter-20261018093000", want: time.Date(2026, 10, 18, 9, 30, 0, 0, time.Local), wantOK: true},
+		{name: "grafana-db-exporter-2026101809300020261018093000", want: time.Date(2026, 10, 18, 9, 30, 0, 0, time.Local), wantOK: true},
+		{name: "grafana-db-exporter-2026"},
+		{name: "grafana-db-exporter-notatimestamp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := BranchTime(tt.name, "grafana-db-exporter-")
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("BranchTime() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
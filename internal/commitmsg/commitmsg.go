@@ -0,0 +1,120 @@
+package commitmsg
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"grafana-db-exporter/internal/changes"
+)
+
+const (
+	PresetConventional = "conventional"
+	PresetSimple       = "simple"
+)
+
+const conventionalTemplate = `chore(grafana): {{if .Total}}update {{.Total}} dashboard{{if ne .Total 1}}s{{end}}{{else}}update Grafana export{{end}}
+{{if .Added}}
+Added:
+{{range .Added}}- {{.Title}} ({{.Path}})
+{{end}}{{end}}{{if .Modified}}
+Modified:
+{{range .Modified}}- {{.Title}} ({{.Path}})
+{{end}}{{end}}{{if .Moved}}
+Moved:
+{{range .Moved}}- {{.Title}} ({{.OldPath}} -> {{.Path}})
+{{end}}{{end}}{{if .Deleted}}
+Deleted:
+{{range .Deleted}}- {{.Title}} ({{.Path}})
+{{end}}{{end}}
+Grafana-URL: {{.GrafanaURL}}
+Export-Branch: {{.Branch}}
+Export-Run-ID: {{.RunID}}
+`
+
+const simpleTemplate = `Update Grafana dashboards`
+
+var presets = map[string]string{
+	"":                 conventionalTemplate,
+	PresetConventional: conventionalTemplate,
+	PresetSimple:       simpleTemplate,
+}
+
+var extraBlankLines = regexp.MustCompile(`\n{3,}`)
+
+var funcs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"titles": func(list []changes.Dashboard) []string {
+		titles := make([]string, 0, len(list))
+		for _, d := range list {
+			titles = append(titles, d.Title)
+		}
+		return titles
+	},
+}
+
+type Data struct {
+	Added      []changes.Dashboard
+	Modified   []changes.Dashboard
+	Moved      []changes.Dashboard
+	Deleted    []changes.Dashboard
+	Total      int
+	GrafanaURL string
+	Branch     string
+	RunID      string
+}
+
+type Template struct {
+	tmpl *template.Template
+}
+
+func NewData(summary *changes.Summary, grafanaURL, branch, runID string) Data {
+	return Data{
+		Added:      summary.Added,
+		Modified:   summary.Modified,
+		Moved:      summary.Moved,
+		Deleted:    summary.Deleted,
+		Total:      len(summary.Added) + len(summary.Modified) + len(summary.Moved) + len(summary.Deleted),
+		GrafanaURL: grafanaURL,
+		Branch:     branch,
+		RunID:      runID,
+	}
+}
+
+// Parse parses text as a commit message template. text may also name a preset: conventional or simple.
+func Parse(text string) (*Template, error) {
+	if preset, ok := presets[text]; ok {
+		text = preset
+	}
+
+	tmpl, err := template.New("commit").Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse commit message template: %w", err)
+	}
+	return &Template{tmpl: tmpl}, nil
+}
+
+func Load(path string) (*Template, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit message template: %w", err)
+	}
+	return Parse(string(raw))
+}
+
+func (t *Template) Render(data Data) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render commit message: %w", err)
+	}
+
+	message := extraBlankLines.ReplaceAllString(strings.TrimSpace(b.String()), "\n\n")
+	if message == "" {
+		return "", fmt.Errorf("commit message template rendered an empty message")
+	}
+	return message, nil
+}
@@ -0,0 +1,123 @@
+package commitmsg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"grafana-db-exporter/internal/changes"
+)
+
+func testData() Data {
+	return NewData(&changes.Summary{
+		Added:    []changes.Dashboard{{Title: "API", Path: "Apps/api.json"}},
+		Modified: []changes.Dashboard{{Title: "Nodes", Path: "Ops/nodes.json"}, {Title: "Disks", Path: "Ops/disks.json"}},
+		Moved:    []changes.Dashboard{{Title: "Logs", OldPath: "Ops/logs.json", Path: "Apps/logs.json"}},
+	}, "https://grafana.example.com", "grafana-db-exporter/sync", "run-1")
+}
+
+func TestTemplate_Render(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		data     Data
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "Conventional preset",
+			template: "",
+			data:     testData(),
+			expected: `chore(grafana): update 4 dashboards
+
+Added:
+- API (Apps/api.json)
+
+Modified:
+- Nodes (Ops/nodes.json)
+- Disks (Ops/disks.json)
+
+Moved:
+- Logs (Ops/logs.json -> Apps/logs.json)
+
+Grafana-URL: https://grafana.example.com
+Export-Branch: grafana-db-exporter/sync
+Export-Run-ID: run-1`,
+		},
+		{
+			name:     "Conventional preset without dashboard changes",
+			template: PresetConventional,
+			data:     NewData(&changes.Summary{}, "https://grafana.example.com", "main", "run-2"),
+			expected: `chore(grafana): update Grafana export
+
+Grafana-URL: https://grafana.example.com
+Export-Branch: main
+Export-Run-ID: run-2`,
+		},
+		{
+			name:     "Simple preset",
+			template: PresetSimple,
+			data:     testData(),
+			expected: "Update Grafana dashboards",
+		},
+		{
+			name:     "Custom template with functions",
+			template: `feat: sync {{len .Modified}} modified ({{join (titles .Modified) ", "}}) on {{upper .Branch}}`,
+			data:     testData(),
+			expected: "feat: sync 2 modified (Nodes, Disks) on GRAFANA-DB-EXPORTER/SYNC",
+		},
+		{
+			name:     "Unknown field",
+			template: `{{.Missing}}`,
+			data:     testData(),
+			wantErr:  true,
+		},
+		{
+			name:     "Empty message",
+			template: `{{if .Deleted}}deleted{{end}}`,
+			data:     testData(),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse(tt.template)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got, err := tmpl.Render(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("Render() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	if _, err := Parse(`{{.Total`); err == nil {
+		t.Errorf("Parse() should return an error for an invalid template")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "commit.tmpl")
+	if err := os.WriteFile(path, []byte("Export {{.RunID}}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	tmpl, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	got, err := tmpl.Render(testData())
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if got != "Export run-1" {
+		t.Errorf("Render() = %q, want %q", got, "Export run-1")
+	}
+}
@@ -9,26 +9,98 @@ import (
 	"strconv"
 	"strings"
 
+	"grafana-db-exporter/internal/git"
 	"grafana-db-exporter/internal/logger"
 )
 
+const (
+	BranchModeNew  = "new"
+	BranchModeBase = "base"
+	BranchModeSync = "sync"
+)
+
+const (
+	OutputModeGit        = "git"
+	OutputModeFilesystem = "filesystem"
+)
+
+const (
+	ConflictActionFail      = "fail"
+	ConflictActionOverwrite = "overwrite"
+)
+
+const (
+	LintBlockNone   = "none"
+	LintBlockCommit = "commit"
+	LintBlockPush   = "push"
+)
+
 type Config struct {
-	SSHURL         string `env:"SSH_URL,required"`
-	SSHKey         string `env:"SSH_KEY,required"`
-	SSHUser        string `env:"SSH_USER,required"`
-	SSHEmail       string `env:"SSH_EMAIL,required"`
+	GitURL         string `env:"GIT_URL"`
+	SSHURL         string `env:"SSH_URL"`
+	SSHKey         string `env:"SSH_KEY"`
+	SSHUser        string `env:"SSH_USER"`
+	SSHEmail       string `env:"SSH_EMAIL"`
 	RepoSavePath   string `env:"REPO_SAVE_PATH,required"`
 	GrafanaURL     string `env:"GRAFANA_URL,required"`
 	GrafanaSaToken string `env:"GRAFANA_SA_TOKEN,required"`
 
 	BaseBranch            string `env:"BASE_BRANCH,default=main"`
 	BranchPrefix          string `env:"BRANCH_PREFIX,default=grafana-db-exporter-"`
+	BranchMode            string `env:"BRANCH_MODE,default=new"`
+	SyncBranch            string `env:"SYNC_BRANCH,default=grafana-db-exporter/sync"`
+	BaseConflictAction    string `env:"BASE_CONFLICT_ACTION,default=fail"`
 	SshKeyPassword        string `env:"SSH_KEY_PASSWORD"`
+	SSHPrivateKey         string `env:"SSH_PRIVATE_KEY"`
+	SSHCertPath           string `env:"SSH_CERT_PATH"`
+	SSHUseAgent           bool   `env:"SSH_USE_AGENT,default=false"`
 	SshAcceptUnknownHosts bool   `env:"SSH_ACCEPT_UNKNOWN_HOSTS,default=false"`
 	SshKnownHostsPath     string `env:"SSH_KNOWN_HOSTS_PATH"`
+	GitUsername           string `env:"GIT_USERNAME,default=x-access-token"`
+	GitToken              string `env:"GIT_TOKEN"`
+
+	GitAuthorName        string `env:"GIT_AUTHOR_NAME"`
+	GitAuthorEmail       string `env:"GIT_AUTHOR_EMAIL"`
+	GitCommitterName     string `env:"GIT_COMMITTER_NAME"`
+	GitCommitterEmail    string `env:"GIT_COMMITTER_EMAIL"`
+	SigningFormat        string `env:"SIGNING_FORMAT"`
+	SigningKeyPath       string `env:"SIGNING_KEY_PATH"`
+	SigningKey           string `env:"SIGNING_KEY"`
+	SigningKeyPassphrase string `env:"SIGNING_KEY_PASSPHRASE"`
+
+	CommitMessageTemplate     string `env:"COMMIT_MESSAGE_TEMPLATE"`
+	CommitMessageTemplatePath string `env:"COMMIT_MESSAGE_TEMPLATE_PATH"`
+	RunID                     string `env:"RUN_ID"`
+
+	PRProvider  string `env:"PR_PROVIDER"`
+	PRToken     string `env:"PR_TOKEN"`
+	PRAPIURL    string `env:"PR_API_URL"`
+	PRTitle     string `env:"PR_TITLE,default=Update Grafana dashboards"`
+	PRBody      string `env:"PR_BODY"`
+	PRLabels    string `env:"PR_LABELS"`
+	PRReviewers string `env:"PR_REVIEWERS"`
+	PRDraft     bool   `env:"PR_DRAFT,default=false"`
 
-	RepoClonePath string `env:"REPO_CLONE_PATH,default=./repo/"`
-	DeleteMissing bool   `env:"DELETE_MISSING,default=true"`
+	PRAssignees          string `env:"PR_ASSIGNEES"`
+	PRRemoveSourceBranch bool   `env:"PR_REMOVE_SOURCE_BRANCH,default=false"`
+	PRAutoMerge          bool   `env:"PR_AUTO_MERGE,default=false"`
+
+	BranchCleanup       bool `env:"BRANCH_CLEANUP,default=false"`
+	BranchRetentionDays uint `env:"BRANCH_RETENTION_DAYS,default=30"`
+	BranchCleanupMerged bool `env:"BRANCH_CLEANUP_MERGED,default=true"`
+	BranchCleanupDryRun bool `env:"BRANCH_CLEANUP_DRY_RUN,default=false"`
+	BranchCleanupMax    uint `env:"BRANCH_CLEANUP_MAX,default=50"`
+
+	ExportTag              bool   `env:"EXPORT_TAG,default=false"`
+	ExportTagTemplate      string `env:"EXPORT_TAG_TEMPLATE"`
+	ExportTagRetentionDays uint   `env:"EXPORT_TAG_RETENTION_DAYS,default=0"`
+
+	OutputMode        string `env:"OUTPUT_MODE,default=git"`
+	RepoClonePath     string `env:"REPO_CLONE_PATH,default=./repo/"`
+	RepoCleanup       bool   `env:"REPO_CLEANUP,default=false"`
+	CloneDepth        uint   `env:"CLONE_DEPTH,default=0"`
+	CloneSingleBranch bool   `env:"CLONE_SINGLE_BRANCH,default=false"`
+	DeleteMissing     bool   `env:"DELETE_MISSING,default=true"`
 
 	EnableRetries  bool `env:"ENABLE_RETRIES,default=true"`
 	NumOfRetries   uint `env:"NUM_OF_RETRIES,default=3"`
@@ -37,6 +109,35 @@ type Config struct {
 	AddMissingNewlines    bool `env:"ADD_MISSING_NEWLINES,default=true"`
 	DryRun                bool `env:"DRY_RUN,default=false"`
 	IgnoreFolderStructure bool `env:"IGNORE_FOLDER_STRUCTURE,default=false"`
+
+	NormalizeDashboards      bool   `env:"NORMALIZE_DASHBOARDS,default=false"`
+	NormalizeResetTemplating bool   `env:"NORMALIZE_RESET_TEMPLATING,default=true"`
+	NormalizeTimeFrom        string `env:"NORMALIZE_TIME_FROM,default=now-6h"`
+	NormalizeTimeTo          string `env:"NORMALIZE_TIME_TO,default=now"`
+	NormalizeStripRefresh    bool   `env:"NORMALIZE_STRIP_REFRESH,default=false"`
+	NormalizeExpandRows      bool   `env:"NORMALIZE_EXPAND_ROWS,default=false"`
+
+	TransformRulesPath string `env:"TRANSFORM_RULES_PATH"`
+	ApplyOverlays      bool   `env:"APPLY_OVERLAYS,default=true"`
+
+	SecretScan         bool   `env:"SECRET_SCAN,default=false"`
+	SecretScanAction   string `env:"SECRET_SCAN_ACTION,default=redact"`
+	SecretPatternsPath string `env:"SECRET_PATTERNS_PATH"`
+
+	Lint           bool   `env:"LINT,default=false"`
+	LintSeverities string `env:"LINT_SEVERITIES"`
+	LintBlock      string `env:"LINT_BLOCK,default=none"`
+
+	ValidateQueries    bool `env:"VALIDATE_QUERIES,default=false"`
+	FailOnInvalidQuery bool `env:"FAIL_ON_INVALID_QUERY,default=false"`
+
+	MirrorsPath string `env:"MIRRORS_PATH"`
+
+	ReportPath    string `env:"REPORT_PATH"`
+	InventoryPath string `env:"INVENTORY_PATH"`
+
+	ChangesOutputPath string `env:"CHANGES_OUTPUT_PATH"`
+	NoChangesExitCode uint   `env:"NO_CHANGES_EXIT_CODE,default=0"`
 }
 
 func Load() (*Config, error) {
@@ -46,9 +147,20 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to parse environment variables: %w", err)
 	}
 
-	cfg.RepoSavePath = filepath.Join(cfg.RepoClonePath, cfg.RepoSavePath)
+	// Without Git there is no clone, so paths are taken as they are.
+	root := cfg.RepoClonePath
+	if cfg.OutputMode == OutputModeFilesystem {
+		root = ""
+	}
+
+	cfg.RepoSavePath = filepath.Join(root, cfg.RepoSavePath)
 	logger.Log.Debug().Str("FullRepoSavePath", cfg.RepoSavePath).Msg("Full RepoSavePath")
 
+	if cfg.InventoryPath != "" {
+		cfg.InventoryPath = filepath.Join(root, cfg.InventoryPath)
+		logger.Log.Debug().Str("FullInventoryPath", cfg.InventoryPath).Msg("Full InventoryPath")
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
@@ -60,24 +172,173 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// RemoteURL returns the Git remote, preferring GIT_URL over the older SSH_URL.
+func (c *Config) RemoteURL() string {
+	if c.GitURL != "" {
+		return c.GitURL
+	}
+	return c.SSHURL
+}
+
 func (c *Config) Validate() error {
 	logger.Log.Debug().Msg("Validating Grafana URL")
 	if _, err := url.ParseRequestURI(c.GrafanaURL); err != nil {
 		return fmt.Errorf("invalid Grafana URL: %w", err)
 	}
 
-	logger.Log.Debug().Str("SSHKeyPath", c.SSHKey).Msg("Checking SSH key file")
-	if _, err := os.Stat(c.SSHKey); os.IsNotExist(err) {
-		return fmt.Errorf("SSH key file does not exist: %s", c.SSHKey)
+	logger.Log.Debug().Str("OutputMode", c.OutputMode).Msg("Checking output mode")
+	switch c.OutputMode {
+	case "", OutputModeGit:
+		if err := c.validateGit(); err != nil {
+			return err
+		}
+	case OutputModeFilesystem:
+	default:
+		return fmt.Errorf("invalid output mode: %s", c.OutputMode)
+	}
+
+	if c.InventoryPath != "" {
+		logger.Log.Debug().Str("InventoryPath", c.InventoryPath).Msg("Checking inventory path")
+		// Files under REPO_SAVE_PATH are taken for dashboards when computing changes and deleting.
+		if rel, err := filepath.Rel(c.RepoSavePath, c.InventoryPath); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("INVENTORY_PATH must not be inside REPO_SAVE_PATH: %s", c.InventoryPath)
+		}
+	}
+
+	if c.TransformRulesPath != "" {
+		logger.Log.Debug().Str("TransformRulesPath", c.TransformRulesPath).Msg("Checking transform rules file")
+		if _, err := os.Stat(c.TransformRulesPath); os.IsNotExist(err) {
+			return fmt.Errorf("transform rules file does not exist: %s", c.TransformRulesPath)
+		}
+	}
+
+	if c.SecretScan {
+		logger.Log.Debug().Str("SecretPatternsPath", c.SecretPatternsPath).Msg("Checking secret scan configuration")
+		if c.SecretPatternsPath != "" {
+			if _, err := os.Stat(c.SecretPatternsPath); os.IsNotExist(err) {
+				return fmt.Errorf("secret patterns file does not exist: %s", c.SecretPatternsPath)
+			}
+		}
+	}
+
+	if c.Lint {
+		logger.Log.Debug().Str("LintBlock", c.LintBlock).Msg("Checking lint configuration")
+		switch c.LintBlock {
+		case LintBlockNone, LintBlockCommit, LintBlockPush:
+		default:
+			return fmt.Errorf("invalid lint block mode: %s", c.LintBlock)
+		}
+	}
+
+	if c.NoChangesExitCode > 255 {
+		return fmt.Errorf("invalid no changes exit code: %d", c.NoChangesExitCode)
+	}
+
+	return nil
+}
+
+// validateGit checks the settings used to clone, commit and push, which the filesystem output mode ignores.
+func (c *Config) validateGit() error {
+	if c.GitAuthorName == "" && c.SSHUser == "" {
+		return fmt.Errorf("SSH_USER or GIT_AUTHOR_NAME is required")
+	}
+	if c.GitAuthorEmail == "" && c.SSHEmail == "" {
+		return fmt.Errorf("SSH_EMAIL or GIT_AUTHOR_EMAIL is required")
+	}
+
+	remoteURL := c.RemoteURL()
+	if remoteURL == "" {
+		return fmt.Errorf("GIT_URL or SSH_URL is required")
+	}
+
+	if git.IsHTTPURL(remoteURL) {
+		logger.Log.Debug().Str("GitUsername", c.GitUsername).Bool("GitToken", c.GitToken != "").Msg("Using HTTP(S) remote")
+	} else {
+		logger.Log.Debug().
+			Str("SSHKeyPath", c.SSHKey).
+			Bool("SSHPrivateKey", c.SSHPrivateKey != "").
+			Bool("SSHUseAgent", c.SSHUseAgent).
+			Msg("Checking SSH key configuration")
+		switch {
+		case c.SSHUseAgent:
+			if os.Getenv("SSH_AUTH_SOCK") == "" {
+				return fmt.Errorf("SSH_AUTH_SOCK must be set when SSH_USE_AGENT is true")
+			}
+		case c.SSHPrivateKey != "", c.SSHKey == "-":
+		case c.SSHKey == "":
+			return fmt.Errorf("SSH_KEY, SSH_PRIVATE_KEY or SSH_USE_AGENT is required for SSH remotes")
+		default:
+			if _, err := os.Stat(c.SSHKey); os.IsNotExist(err) {
+				return fmt.Errorf("SSH key file does not exist: %s", c.SSHKey)
+			}
+		}
+		if c.SSHCertPath != "" {
+			if _, err := os.Stat(c.SSHCertPath); os.IsNotExist(err) {
+				return fmt.Errorf("SSH certificate file does not exist: %s", c.SSHCertPath)
+			}
+		}
+
+		logger.Log.Debug().
+			Bool("SshAcceptUnknownHosts", c.SshAcceptUnknownHosts).
+			Str("SshKnownHostsPath", c.SshKnownHostsPath).
+			Msg("Checking SSH known hosts configuration")
+		if !c.SshAcceptUnknownHosts && c.SshKnownHostsPath != "" {
+			if _, err := os.Stat(c.SshKnownHostsPath); os.IsNotExist(err) {
+				return fmt.Errorf("SSH known hosts file does not exist: %s", c.SshKnownHostsPath)
+			}
+		}
+	}
+
+	logger.Log.Debug().Str("BranchMode", c.BranchMode).Msg("Checking branch mode")
+	switch c.BranchMode {
+	case "", BranchModeNew, BranchModeBase:
+	case BranchModeSync:
+		if c.SyncBranch == "" || c.SyncBranch == c.BaseBranch {
+			return fmt.Errorf("sync branch must be set and differ from the base branch")
+		}
+	default:
+		return fmt.Errorf("invalid branch mode: %s", c.BranchMode)
+	}
+
+	switch c.BaseConflictAction {
+	case "", ConflictActionFail, ConflictActionOverwrite:
+	default:
+		return fmt.Errorf("invalid base conflict action: %s", c.BaseConflictAction)
+	}
+
+	if c.SigningFormat != "" {
+		logger.Log.Debug().Str("SigningFormat", c.SigningFormat).Str("SigningKeyPath", c.SigningKeyPath).Msg("Checking commit signing configuration")
+		if c.SigningKeyPath == "" && c.SigningKey == "" {
+			return fmt.Errorf("SIGNING_KEY_PATH or SIGNING_KEY is required when SIGNING_FORMAT is set")
+		}
+	}
+
+	if c.CommitMessageTemplatePath != "" {
+		logger.Log.Debug().Str("CommitMessageTemplatePath", c.CommitMessageTemplatePath).Msg("Checking commit message template file")
+		if _, err := os.Stat(c.CommitMessageTemplatePath); os.IsNotExist(err) {
+			return fmt.Errorf("commit message template file does not exist: %s", c.CommitMessageTemplatePath)
+		}
+	}
+
+	if c.PRProvider != "" {
+		logger.Log.Debug().Str("PRProvider", c.PRProvider).Str("PRAPIURL", c.PRAPIURL).Msg("Checking pull request configuration")
+		if c.PRToken == "" {
+			return fmt.Errorf("PR_TOKEN is required when PR_PROVIDER is set")
+		}
+		if c.BranchMode == BranchModeBase {
+			return fmt.Errorf("pull requests cannot be opened with BRANCH_MODE=base")
+		}
+		if c.PRAPIURL != "" {
+			if _, err := url.ParseRequestURI(c.PRAPIURL); err != nil {
+				return fmt.Errorf("invalid pull request API URL: %w", err)
+			}
+		}
 	}
 
-	logger.Log.Debug().
-		Bool("SshAcceptUnknownHosts", c.SshAcceptUnknownHosts).
-		Str("SshKnownHostsPath", c.SshKnownHostsPath).
-		Msg("Checking SSH known hosts configuration")
-	if !c.SshAcceptUnknownHosts && c.SshKnownHostsPath != "" {
-		if _, err := os.Stat(c.SshKnownHostsPath); os.IsNotExist(err) {
-			return fmt.Errorf("SSH known hosts file does not exist: %s", c.SshKnownHostsPath)
+	if c.MirrorsPath != "" {
+		logger.Log.Debug().Str("MirrorsPath", c.MirrorsPath).Msg("Checking mirrors")
+		if _, err := os.Stat(c.MirrorsPath); os.IsNotExist(err) {
+			return fmt.Errorf("mirrors file does not exist: %s", c.MirrorsPath)
 		}
 	}
 
@@ -0,0 +1,60 @@
+package forge
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"grafana-db-exporter/internal/forge/forgetest"
+)
+
+func TestClient_Do(t *testing.T) {
+	server := forgetest.NewServer(t, "Authorization", "token secret", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/items":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":7}`))
+		case "/api/broken":
+			_, _ = w.Write([]byte(`not json`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"not found"}`))
+		}
+	})
+
+	tests := []struct {
+		name    string
+		token   string
+		path    string
+		body    interface{}
+		wantID  int
+		wantErr bool
+	}{
+		{name: "Created", token: "token secret", path: "/items", body: map[string]string{"name": "a"}, wantID: 7},
+		{name: "Without body", token: "token secret", path: "/items", wantID: 7},
+		{name: "Not found", token: "token secret", path: "/missing", wantErr: true},
+		{name: "Unauthorized", token: "token wrong", path: "/items", wantErr: true},
+		{name: "Invalid response", token: "token secret", path: "/broken", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(server.URL+"/api/", map[string]string{"Authorization": tt.token})
+
+			var out struct {
+				ID int `json:"id"`
+			}
+			err := client.Do(context.Background(), http.MethodPost, tt.path, tt.body, &out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if out.ID != tt.wantID {
+				t.Errorf("Do() decoded id %d, want %d", out.ID, tt.wantID)
+			}
+		})
+	}
+
+	if req := server.Request(http.MethodPost, "/api/items"); req == nil || req.Body["name"] != "a" {
+		t.Errorf("Do() sent request %+v", req)
+	}
+}
@@ -0,0 +1,73 @@
+// Package forgetest provides a recording HTTP server for testing forge API clients.
+package forgetest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+	Body   map[string]interface{}
+}
+
+type Server struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []Request
+}
+
+// NewServer starts a server that rejects requests without the header value with
+// 401, and records and passes all others to handler. The JSON request body is
+// consumed while recording.
+func NewServer(t *testing.T, header, value string, handler http.HandlerFunc) *Server {
+	t.Helper()
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(header) != value {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var body map[string]interface{}
+		if r.Body != nil {
+			_ = json.NewDecoder(r.Body).Decode(&body)
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.EscapedPath(), Query: r.URL.RawQuery, Body: body})
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		handler(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Request returns the first recorded request for method and path, or nil.
+func (s *Server) Request(method, path string) *Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.requests {
+		if s.requests[i].Method == method && s.requests[i].Path == path {
+			return &s.requests[i]
+		}
+	}
+	return nil
+}
+
+// Calls lists the recorded requests as "METHOD path".
+func (s *Server) Calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var calls []string
+	for _, r := range s.requests {
+		calls = append(calls, r.Method+" "+r.Path)
+	}
+	return calls
+}
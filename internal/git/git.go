@@ -2,8 +2,13 @@ package git
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -11,6 +16,8 @@ import (
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
 	gogitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/knownhosts"
@@ -18,69 +25,315 @@ import (
 	"grafana-db-exporter/internal/logger"
 )
 
+var ErrNonFastForward = errors.New("push rejected as non-fast-forward")
+
+var scpLikeURL = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+
 type Client struct {
-	repo *git.Repository
-	auth *gogitssh.PublicKeys
+	repo        *git.Repository
+	auth        transport.AuthMethod
+	depth       int
+	remoteAuths map[string]transport.AuthMethod
+}
+
+// AuthOptions configures how the remote is accessed. The SSH settings are used for SSH remotes,
+// Username and Token for HTTP(S) remotes.
+type AuthOptions struct {
+	// SSHKeyPath is read from stdin when set to "-". SSHKey takes precedence if set.
+	SSHKeyPath         string
+	SSHKey             string
+	SSHKeyPassword     string
+	SSHCertPath        string
+	UseSSHAgent        bool
+	KnownHostsPath     string
+	AcceptUnknownHosts bool
+	Username           string
+	Token              string
+}
+
+// CloneOptions controls how REPO_CLONE_PATH is prepared. An existing clone of the same remote is
+// reused and reset to BaseBranch; Cleanup also removes untracked files and other local branches.
+// Depth > 0 limits the history fetched, and SingleBranch only clones BaseBranch.
+type CloneOptions struct {
+	BaseBranch   string
+	Cleanup      bool
+	Depth        int
+	SingleBranch bool
 }
 
-func New(repoClonePath, sshURL, sshKeyPath, sshKeyPassword, knownHostsPath string, allowUnknownHosts bool) (*Client, error) {
+func New(ctx context.Context, repoClonePath, remoteURL string, authOpts AuthOptions, cloneOpts CloneOptions) (*Client, error) {
 	logger.Log.Debug().
 		Str("repoClonePath", repoClonePath).
-		Str("sshURL", sshURL).
-		Str("sshKeyPath", sshKeyPath).
-		Str("knownHostsPath", knownHostsPath).
-		Bool("allowUnknownHosts", allowUnknownHosts).
+		Str("remoteURL", remoteURL).
 		Msg("Creating new Git client")
 
-	sshKey, err := os.ReadFile(sshKeyPath)
+	auth, err := newAuth(remoteURL, authOpts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read SSH key: %w", err)
+		return nil, err
+	}
+	logger.Log.Debug().Msg("Git client set up successfully")
+
+	repo, err := git.PlainOpen(repoClonePath)
+	switch {
+	case err == nil:
+		logger.Log.Info().Str("repoClonePath", repoClonePath).Msg("Reusing existing clone")
+		client := &Client{repo: repo, auth: auth, depth: cloneOpts.Depth}
+		if err := client.reuse(ctx, remoteURL, cloneOpts); err != nil {
+			return nil, err
+		}
+		return client, nil
+	case errors.Is(err, git.ErrRepositoryNotExists):
+	default:
+		return nil, fmt.Errorf("failed to open existing clone: %w", err)
 	}
 
-	sshKey = []byte(strings.TrimSpace(string(sshKey)))
+	logger.Log.Debug().
+		Str("repoClonePath", repoClonePath).
+		Str("remoteURL", remoteURL).
+		Int("depth", cloneOpts.Depth).
+		Bool("singleBranch", cloneOpts.SingleBranch).
+		Msg("Cloning repository")
+	repo, err = git.PlainCloneContext(ctx, repoClonePath, false, &git.CloneOptions{
+		URL:           remoteURL,
+		Auth:          auth,
+		ReferenceName: plumbing.NewBranchReferenceName(cloneOpts.BaseBranch),
+		SingleBranch:  cloneOpts.SingleBranch,
+		Depth:         cloneOpts.Depth,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone repository: %w", err)
+	}
+	logger.Log.Debug().Msg("Repository cloned successfully")
+
+	return &Client{repo: repo, auth: auth, depth: cloneOpts.Depth}, nil
+}
+
+// reuse discards local changes in an existing clone and resets it to the remote base branch.
+func (gc *Client) reuse(ctx context.Context, remoteURL string, opts CloneOptions) error {
+	remote, err := gc.repo.Remote("origin")
+	if err != nil {
+		return fmt.Errorf("failed to get origin of existing clone: %w", err)
+	}
+	if urls := remote.Config().URLs; len(urls) == 0 || urls[0] != remoteURL {
+		return fmt.Errorf("existing clone has origin %v, expected %s", urls, remoteURL)
+	}
+
+	w, err := gc.repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+	if err := w.Reset(&git.ResetOptions{Mode: git.HardReset}); err != nil {
+		return fmt.Errorf("failed to discard local changes: %w", err)
+	}
+
+	if err := gc.ResetToRemote(ctx, opts.BaseBranch); err != nil {
+		return err
+	}
+
+	if !opts.Cleanup {
+		return nil
+	}
+
+	logger.Log.Debug().Msg("Removing untracked files")
+	if err := w.Clean(&git.CleanOptions{Dir: true}); err != nil {
+		return fmt.Errorf("failed to remove untracked files: %w", err)
+	}
+
+	branches, err := gc.repo.Branches()
+	if err != nil {
+		return fmt.Errorf("failed to list branches: %w", err)
+	}
+	var stale []plumbing.ReferenceName
+	err = branches.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name().Short() != opts.BaseBranch {
+			stale = append(stale, ref.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list branches: %w", err)
+	}
+	for _, name := range stale {
+		logger.Log.Debug().Str("branch", name.Short()).Msg("Removing stale local branch")
+		if err := gc.repo.Storer.RemoveReference(name); err != nil {
+			return fmt.Errorf("failed to remove branch %s: %w", name.Short(), err)
+		}
+	}
+	return nil
+}
+
+// IsHTTPURL reports whether remoteURL uses the HTTP(S) transport.
+func IsHTTPURL(remoteURL string) bool {
+	lower := strings.ToLower(remoteURL)
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://")
+}
+
+func newAuth(remoteURL string, opts AuthOptions) (transport.AuthMethod, error) {
+	if IsHTTPURL(remoteURL) {
+		if opts.Token == "" {
+			logger.Log.Debug().Msg("Using HTTP(S) transport without authentication")
+			return nil, nil
+		}
+		logger.Log.Debug().Str("username", opts.Username).Msg("Using HTTP(S) token authentication")
+		return &githttp.BasicAuth{Username: opts.Username, Password: opts.Token}, nil
+	}
+	return newSSHAuth(sshUser(remoteURL), opts)
+}
+
+// sshUser returns the user of ssh://user@host/repo and user@host:repo remotes, or git.
+func sshUser(remoteURL string) string {
+	if strings.Contains(remoteURL, "://") {
+		if u, err := url.Parse(remoteURL); err == nil && u.User != nil && u.User.Username() != "" {
+			return u.User.Username()
+		}
+		return "git"
+	}
+
+	host, _, _ := strings.Cut(remoteURL, ":")
+	if user, _, ok := strings.Cut(host, "@"); ok && user != "" {
+		return user
+	}
+	return "git"
+}
+
+func newSSHAuth(user string, opts AuthOptions) (transport.AuthMethod, error) {
+	logger.Log.Debug().
+		Str("user", user).
+		Str("sshKeyPath", opts.SSHKeyPath).
+		Bool("sshKeyFromEnv", opts.SSHKey != "").
+		Str("sshCertPath", opts.SSHCertPath).
+		Bool("useSSHAgent", opts.UseSSHAgent).
+		Str("knownHostsPath", opts.KnownHostsPath).
+		Bool("allowUnknownHosts", opts.AcceptUnknownHosts).
+		Msg("Using SSH authentication")
+
+	hostKeyCallback, err := newHostKeyCallback(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.UseSSHAgent {
+		logger.Log.Debug().Msg("Using SSH agent from SSH_AUTH_SOCK")
+		auth, err := gogitssh.NewSSHAgentAuth(user)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to SSH agent: %w", err)
+		}
+		auth.HostKeyCallback = hostKeyCallback
+		return auth, nil
+	}
+
+	sshKey, err := readSSHKey(opts)
+	if err != nil {
+		return nil, err
+	}
 
 	var signer ssh.Signer
-	if sshKeyPassword == "" {
+	if opts.SSHKeyPassword == "" {
 		logger.Log.Debug().Msg("Parsing SSH private key without password")
 		signer, err = parseSSHPrivateKey(sshKey)
 	} else {
 		logger.Log.Debug().Msg("Parsing SSH private key with password")
-		signer, err = ssh.ParsePrivateKeyWithPassphrase(sshKey, []byte(sshKeyPassword))
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(sshKey, []byte(opts.SSHKeyPassword))
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse SSH key: %w", err)
 	}
 	logger.Log.Debug().Msg("SSH key parsed successfully")
 
-	auth := &gogitssh.PublicKeys{User: "git", Signer: signer}
-
-	if allowUnknownHosts {
-		logger.Log.Debug().Msg("Allowing unknown hosts")
-		auth.HostKeyCallback = ssh.InsecureIgnoreHostKey()
-	} else {
-		logger.Log.Debug().Str("knownHostsPath", knownHostsPath).Msg("Using known hosts file")
-		if _, err := os.Stat(knownHostsPath); os.IsNotExist(err) {
-			return nil, fmt.Errorf("known hosts file does not exist and allowUnknownHosts is false: %w", err)
+	certPath := opts.SSHCertPath
+	if certPath == "" && opts.SSHKey == "" && opts.SSHKeyPath != "-" {
+		// Like OpenSSH, pick up a certificate stored next to the key.
+		if _, err := os.Stat(opts.SSHKeyPath + "-cert.pub"); err == nil {
+			certPath = opts.SSHKeyPath + "-cert.pub"
 		}
-		hostKeyCallback, err := knownhosts.New(knownHostsPath)
+	}
+	if certPath != "" {
+		signer, err = newCertSigner(signer, certPath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create known hosts callback: %w", err)
+			return nil, err
 		}
-		auth.HostKeyCallback = hostKeyCallback
 	}
-	logger.Log.Debug().Msg("Git client set up successfully")
 
-	logger.Log.Debug().Str("repoClonePath", repoClonePath).Str("sshURL", sshURL).Msg("Cloning repository")
-	repo, err := git.PlainClone(repoClonePath, false, &git.CloneOptions{
-		URL:  sshURL,
-		Auth: auth,
-	})
+	auth := &gogitssh.PublicKeys{User: user, Signer: signer}
+	auth.HostKeyCallback = hostKeyCallback
+	return auth, nil
+}
+
+func readSSHKey(opts AuthOptions) ([]byte, error) {
+	var sshKey []byte
+	var err error
+	switch {
+	case opts.SSHKey != "":
+		sshKey = []byte(opts.SSHKey)
+	case opts.SSHKeyPath == "-":
+		logger.Log.Debug().Msg("Reading SSH key from stdin")
+		sshKey, err = io.ReadAll(os.Stdin)
+	default:
+		sshKey, err = os.ReadFile(opts.SSHKeyPath)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to clone repository: %w", err)
+		return nil, fmt.Errorf("failed to read SSH key: %w", err)
+	}
+	return []byte(strings.TrimSpace(string(sshKey))), nil
+}
+
+func newCertSigner(signer ssh.Signer, certPath string) (ssh.Signer, error) {
+	logger.Log.Debug().Str("certPath", certPath).Msg("Using SSH certificate")
+	raw, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH certificate: %w", err)
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH certificate: %w", err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an SSH certificate", certPath)
+	}
+	certSigner, err := ssh.NewCertSigner(cert, signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use SSH certificate: %w", err)
+	}
+	return certSigner, nil
+}
+
+func newHostKeyCallback(opts AuthOptions) (ssh.HostKeyCallback, error) {
+	if opts.AcceptUnknownHosts {
+		logger.Log.Debug().Msg("Allowing unknown hosts")
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	logger.Log.Debug().Str("knownHostsPath", opts.KnownHostsPath).Msg("Using known hosts file")
+	if _, err := os.Stat(opts.KnownHostsPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("known hosts file does not exist and allowUnknownHosts is false: %w", err)
+	}
+	hostKeyCallback, err := knownhosts.New(opts.KnownHostsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create known hosts callback: %w", err)
+	}
+	return hostKeyCallback, nil
+}
+
+// ParseRemoteURL returns the host and the repository path without the .git suffix of an
+// scp-like SSH, ssh:// or http(s):// remote URL.
+func ParseRemoteURL(remoteURL string) (string, string, error) {
+	var host, path string
+	if strings.Contains(remoteURL, "://") {
+		u, err := url.Parse(remoteURL)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to parse remote URL: %w", err)
+		}
+		host, path = u.Hostname(), u.Path
+	} else if m := scpLikeURL.FindStringSubmatch(remoteURL); m != nil {
+		host, path = m[1], m[2]
 	}
-	logger.Log.Debug().Msg("Repository cloned successfully")
 
-	return &Client{repo: repo, auth: auth}, nil
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || path == "" {
+		return "", "", fmt.Errorf("unsupported remote URL: %s", remoteURL)
+	}
+	return host, path, nil
 }
 
 func parseSSHPrivateKey(privateKey []byte) (ssh.Signer, error) {
@@ -114,7 +367,243 @@ func (gc *Client) CheckoutNewBranch(ctx context.Context, baseBranch, branchPrefi
 	return newBranch, nil
 }
 
-func (gc *Client) CommitAll(ctx context.Context, sshUsername, sshEmail string) error {
+func (gc *Client) CheckoutBranch(ctx context.Context, branch string) error {
+	w, err := gc.repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	logger.Log.Debug().Str("branch", branch).Msg("Checking out branch")
+	err = w.Checkout(&git.CheckoutOptions{
+		Branch: plumbing.NewBranchReferenceName(branch),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to checkout branch %s: %w", branch, err)
+	}
+
+	return nil
+}
+
+// ResetToRemote fetches branch from origin and hard-resets the local branch to it.
+func (gc *Client) ResetToRemote(ctx context.Context, branch string) error {
+	if _, err := gc.fetchBranch(ctx, branch); err != nil {
+		return err
+	}
+
+	remoteRef, err := gc.repo.Reference(plumbing.NewRemoteReferenceName("origin", branch), true)
+	if err != nil {
+		return fmt.Errorf("failed to resolve remote branch %s: %w", branch, err)
+	}
+
+	if err := gc.CheckoutBranch(ctx, branch); err != nil {
+		return err
+	}
+
+	w, err := gc.repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+	if err := w.Reset(&git.ResetOptions{Commit: remoteRef.Hash(), Mode: git.HardReset}); err != nil {
+		return fmt.Errorf("failed to reset branch %s: %w", branch, err)
+	}
+
+	logger.Log.Debug().Str("branch", branch).Str("commit", remoteRef.Hash().String()).Msg("Branch reset to remote")
+	return nil
+}
+
+// ConflictingFiles fetches branch and returns the files changed both by the HEAD commit and on the
+// remote branch since the parent of HEAD, i.e. files that re-applying HEAD on top of the remote would overwrite.
+func (gc *Client) ConflictingFiles(ctx context.Context, branch string) ([]string, error) {
+	head, err := gc.repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	headCommit, err := gc.repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+	if headCommit.NumParents() == 0 {
+		return nil, nil
+	}
+	base, err := headCommit.Parent(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get parent commit: %w", err)
+	}
+
+	if _, err := gc.fetchBranch(ctx, branch); err != nil {
+		return nil, err
+	}
+	remoteRef, err := gc.repo.Reference(plumbing.NewRemoteReferenceName("origin", branch), true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve remote branch %s: %w", branch, err)
+	}
+	remoteCommit, err := gc.repo.CommitObject(remoteRef.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get remote commit: %w", err)
+	}
+
+	local, err := diffFiles(base, headCommit)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := diffFiles(base, remoteCommit)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []string
+	for path := range local {
+		if remote[path] {
+			conflicts = append(conflicts, path)
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts, nil
+}
+
+func diffFiles(from, to *object.Commit) (map[string]bool, error) {
+	fromTree, err := from.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree of %s: %w", from.Hash, err)
+	}
+	toTree, err := to.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree of %s: %w", to.Hash, err)
+	}
+
+	changes, err := object.DiffTree(fromTree, toTree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff %s and %s: %w", from.Hash, to.Hash, err)
+	}
+
+	files := make(map[string]bool, len(changes))
+	for _, change := range changes {
+		if change.From.Name != "" {
+			files[change.From.Name] = true
+		}
+		if change.To.Name != "" {
+			files[change.To.Name] = true
+		}
+	}
+	return files, nil
+}
+
+// CheckoutSyncBranch checks out syncBranch reset to the current state of baseBranch. The remote
+// sync branch is fetched so that PushWithLease only overwrites the state seen here.
+func (gc *Client) CheckoutSyncBranch(ctx context.Context, baseBranch, syncBranch string) error {
+	if err := gc.CheckoutBranch(ctx, baseBranch); err != nil {
+		return err
+	}
+
+	head, err := gc.repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	exists, err := gc.fetchBranch(ctx, syncBranch)
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Str("syncBranch", syncBranch).Bool("remoteExists", exists).Msg("Fetched sync branch")
+
+	ref := plumbing.NewHashReference(plumbing.NewBranchReferenceName(syncBranch), head.Hash())
+	if err := gc.repo.Storer.SetReference(ref); err != nil {
+		return fmt.Errorf("failed to reset sync branch: %w", err)
+	}
+
+	w, err := gc.repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+	err = w.Checkout(&git.CheckoutOptions{
+		Branch: ref.Name(),
+		Force:  true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to checkout sync branch: %w", err)
+	}
+
+	logger.Log.Debug().Str("syncBranch", syncBranch).Str("baseBranch", baseBranch).Msg("Sync branch reset to base branch")
+	return nil
+}
+
+// MatchesRemote reports whether HEAD has the same tree as origin/<branch> as last fetched, i.e.
+// pushing it would not change any files on the remote branch.
+func (gc *Client) MatchesRemote(branch string) (bool, error) {
+	remoteRef, err := gc.repo.Reference(plumbing.NewRemoteReferenceName("origin", branch), true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve remote branch %s: %w", branch, err)
+	}
+	remoteCommit, err := gc.repo.CommitObject(remoteRef.Hash())
+	if err != nil {
+		return false, fmt.Errorf("failed to get commit of remote branch %s: %w", branch, err)
+	}
+
+	head, err := gc.repo.Head()
+	if err != nil {
+		return false, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	headCommit, err := gc.repo.CommitObject(head.Hash())
+	if err != nil {
+		return false, fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+	return headCommit.TreeHash == remoteCommit.TreeHash, nil
+}
+
+// fetchBranch updates the remote-tracking reference of branch and reports whether the branch exists on origin.
+func (gc *Client) fetchBranch(ctx context.Context, branch string) (bool, error) {
+	logger.Log.Debug().Str("branch", branch).Msg("Fetching remote branch")
+	err := gc.repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", branch, branch))},
+		Auth:       gc.auth,
+		Depth:      gc.depth,
+	})
+	switch {
+	case err == nil, errors.Is(err, git.NoErrAlreadyUpToDate):
+		return true, nil
+	case errors.Is(err, git.NoMatchingRefSpecError{}):
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to fetch branch %s: %w", branch, err)
+	}
+}
+
+// ChangedFiles returns the paths that differ from HEAD in the index or the worktree, including untracked files.
+func (gc *Client) ChangedFiles(ctx context.Context) ([]string, error) {
+	w, err := gc.repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	status, err := w.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree status: %w", err)
+	}
+
+	var files []string
+	for path, fileStatus := range status {
+		if fileStatus.Staging != git.Unmodified || fileStatus.Worktree != git.Unmodified {
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// CommitOptions sets the identities and optional signer of a commit. The committer defaults to the author.
+type CommitOptions struct {
+	AuthorName     string
+	AuthorEmail    string
+	CommitterName  string
+	CommitterEmail string
+	Signer         git.Signer
+}
+
+func (gc *Client) CommitAll(ctx context.Context, message string, opts CommitOptions) error {
 	w, err := gc.repo.Worktree()
 	if err != nil {
 		return fmt.Errorf("failed to get worktree: %w", err)
@@ -125,13 +614,18 @@ func (gc *Client) CommitAll(ctx context.Context, sshUsername, sshEmail string) e
 		return fmt.Errorf("failed to add files: %w", err)
 	}
 
-	_, err = w.Commit("Update Grafana dashboards", &git.CommitOptions{
-		All: true,
-		Author: &object.Signature{
-			Name:  sshUsername,
-			Email: sshEmail,
-			When:  time.Now(),
-		},
+	now := time.Now()
+	author := &object.Signature{Name: opts.AuthorName, Email: opts.AuthorEmail, When: now}
+	committer := author
+	if opts.CommitterName != "" || opts.CommitterEmail != "" {
+		committer = &object.Signature{Name: opts.CommitterName, Email: opts.CommitterEmail, When: now}
+	}
+
+	_, err = w.Commit(message, &git.CommitOptions{
+		All:       true,
+		Author:    author,
+		Committer: committer,
+		Signer:    opts.Signer,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to commit changes: %w", err)
@@ -141,14 +635,295 @@ func (gc *Client) CommitAll(ctx context.Context, sshUsername, sshEmail string) e
 }
 
 func (gc *Client) Push(ctx context.Context, branchName string) error {
-	err := gc.repo.PushContext(ctx, &git.PushOptions{
+	return gc.push(ctx, &git.PushOptions{
 		RemoteName: "origin",
 		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("refs/heads/%s:refs/heads/%s", branchName, branchName))},
 		Auth:       gc.auth,
 	})
+}
+
+// AddRemote configures an additional remote, replacing any existing remote of the same name, to be
+// pushed to with PushRemote.
+func (gc *Client) AddRemote(name, remoteURL string, opts AuthOptions) error {
+	auth, err := newAuth(remoteURL, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := gc.repo.DeleteRemote(name); err != nil && !errors.Is(err, git.ErrRemoteNotFound) {
+		return fmt.Errorf("failed to remove existing remote %s: %w", name, err)
+	}
+	if _, err := gc.repo.CreateRemote(&config.RemoteConfig{Name: name, URLs: []string{remoteURL}}); err != nil {
+		return fmt.Errorf("failed to add remote %s: %w", name, err)
+	}
+
+	if gc.remoteAuths == nil {
+		gc.remoteAuths = make(map[string]transport.AuthMethod)
+	}
+	gc.remoteAuths[name] = auth
+	logger.Log.Debug().Str("remote", name).Str("remoteURL", remoteURL).Msg("Added remote")
+	return nil
+}
+
+// PushRemote pushes branchName to a remote added with AddRemote, overwriting the remote branch if force is set.
+func (gc *Client) PushRemote(ctx context.Context, remoteName, branchName string, force bool) error {
+	auth, err := gc.remoteAuth(remoteName)
 	if err != nil {
+		return err
+	}
+
+	refSpec := fmt.Sprintf("refs/heads/%s:refs/heads/%s", branchName, branchName)
+	if force {
+		refSpec = "+" + refSpec
+	}
+	return gc.push(ctx, &git.PushOptions{
+		RemoteName: remoteName,
+		RefSpecs:   []config.RefSpec{config.RefSpec(refSpec)},
+		Auth:       auth,
+	})
+}
+
+func (gc *Client) remoteAuth(remoteName string) (transport.AuthMethod, error) {
+	if remoteName == "origin" {
+		return gc.auth, nil
+	}
+	auth, ok := gc.remoteAuths[remoteName]
+	if !ok {
+		return nil, fmt.Errorf("unknown remote: %s", remoteName)
+	}
+	return auth, nil
+}
+
+func (gc *Client) push(ctx context.Context, opts *git.PushOptions) error {
+	if err := gc.repo.PushContext(ctx, opts); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		if isNonFastForward(err) {
+			return fmt.Errorf("%w: %v", ErrNonFastForward, err)
+		}
 		return fmt.Errorf("failed to push changes: %w", err)
 	}
 
 	return nil
 }
+
+// PushWithLease force-pushes branchName as long as the remote branch still points at the commit
+// last fetched into its remote-tracking reference.
+func (gc *Client) PushWithLease(ctx context.Context, branchName string) error {
+	opts := &git.PushOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/heads/%s", branchName, branchName))},
+		Auth:       gc.auth,
+	}
+
+	trackingRef, err := gc.repo.Reference(plumbing.NewRemoteReferenceName("origin", branchName), true)
+	switch {
+	case err == nil:
+		opts.ForceWithLease = &git.ForceWithLease{
+			RefName: plumbing.NewBranchReferenceName(branchName),
+			Hash:    trackingRef.Hash(),
+		}
+	case errors.Is(err, plumbing.ErrReferenceNotFound):
+		// The branch has never been pushed, so it may only be created, not overwritten.
+		opts.RefSpecs = []config.RefSpec{config.RefSpec(fmt.Sprintf("refs/heads/%s:refs/heads/%s", branchName, branchName))}
+	default:
+		return fmt.Errorf("failed to resolve remote branch %s: %w", branchName, err)
+	}
+
+	return gc.push(ctx, opts)
+}
+
+type RemoteBranch struct {
+	Name   string
+	Hash   plumbing.Hash
+	Merged bool
+}
+
+// ListRemoteBranches lists the branches on origin whose name starts with prefix. With checkMerged,
+// they are fetched to report whether they are already contained in baseBranch.
+func (gc *Client) ListRemoteBranches(ctx context.Context, prefix, baseBranch string, checkMerged bool) ([]RemoteBranch, error) {
+	remote, err := gc.repo.Remote("origin")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get origin: %w", err)
+	}
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: gc.auth})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote branches: %w", err)
+	}
+
+	var branches []RemoteBranch
+	var refSpecs []config.RefSpec
+	for _, ref := range refs {
+		name := ref.Name().Short()
+		if !ref.Name().IsBranch() || !strings.HasPrefix(name, prefix) || name == baseBranch {
+			continue
+		}
+		branches = append(branches, RemoteBranch{Name: name, Hash: ref.Hash()})
+		refSpecs = append(refSpecs, config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", name, name)))
+	}
+	sort.Slice(branches, func(i, j int) bool { return branches[i].Name < branches[j].Name })
+	if !checkMerged || len(branches) == 0 {
+		return branches, nil
+	}
+
+	if _, err := gc.fetchBranch(ctx, baseBranch); err != nil {
+		return nil, err
+	}
+	err = gc.repo.FetchContext(ctx, &git.FetchOptions{RemoteName: "origin", RefSpecs: refSpecs, Auth: gc.auth})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, fmt.Errorf("failed to fetch remote branches: %w", err)
+	}
+
+	baseRef, err := gc.repo.Reference(plumbing.NewRemoteReferenceName("origin", baseBranch), true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve remote branch %s: %w", baseBranch, err)
+	}
+	baseCommit, err := gc.repo.CommitObject(baseRef.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get base commit: %w", err)
+	}
+	for i := range branches {
+		commit, err := gc.repo.CommitObject(branches[i].Hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get commit of %s: %w", branches[i].Name, err)
+		}
+		// History cut off by a shallow clone reports the branch as not merged.
+		merged, err := commit.IsAncestor(baseCommit)
+		if err != nil && !errors.Is(err, plumbing.ErrObjectNotFound) {
+			return nil, fmt.Errorf("failed to check whether %s is merged: %w", branches[i].Name, err)
+		}
+		branches[i].Merged = merged
+	}
+	return branches, nil
+}
+
+// DeleteRemoteBranches deletes branches from origin in a single push.
+func (gc *Client) DeleteRemoteBranches(ctx context.Context, branches []string) error {
+	refNames := make([]plumbing.ReferenceName, 0, len(branches))
+	for _, branch := range branches {
+		refNames = append(refNames, plumbing.NewBranchReferenceName(branch))
+	}
+	if err := gc.deleteRemoteRefs(ctx, refNames); err != nil {
+		return fmt.Errorf("failed to delete remote branches: %w", err)
+	}
+	return nil
+}
+
+// CreateTag creates an annotated tag on HEAD, tagged by the committer of opts.
+func (gc *Client) CreateTag(name, message string, opts CommitOptions) error {
+	head, err := gc.repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	tagger := &object.Signature{Name: opts.AuthorName, Email: opts.AuthorEmail, When: time.Now()}
+	if opts.CommitterName != "" || opts.CommitterEmail != "" {
+		tagger.Name, tagger.Email = opts.CommitterName, opts.CommitterEmail
+	}
+
+	if _, err := gc.repo.CreateTag(name, head.Hash(), &git.CreateTagOptions{Tagger: tagger, Message: message}); err != nil {
+		return fmt.Errorf("failed to create tag %s: %w", name, err)
+	}
+	logger.Log.Debug().Str("tag", name).Str("commit", head.Hash().String()).Msg("Created tag")
+	return nil
+}
+
+// PushTag pushes a tag to origin or to a remote added with AddRemote.
+func (gc *Client) PushTag(ctx context.Context, remoteName, name string) error {
+	auth, err := gc.remoteAuth(remoteName)
+	if err != nil {
+		return err
+	}
+	return gc.push(ctx, &git.PushOptions{
+		RemoteName: remoteName,
+		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("refs/tags/%s:refs/tags/%s", name, name))},
+		Auth:       auth,
+	})
+}
+
+type RemoteTag struct {
+	Name string
+	// Created is the tagger date of annotated tags and zero for lightweight tags.
+	Created time.Time
+}
+
+// ListRemoteTags lists the tags on origin whose name starts with prefix, fetching them to read their
+// tagger date.
+func (gc *Client) ListRemoteTags(ctx context.Context, prefix string) ([]RemoteTag, error) {
+	remote, err := gc.repo.Remote("origin")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get origin: %w", err)
+	}
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: gc.auth})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote tags: %w", err)
+	}
+
+	var tags []RemoteTag
+	var hashes []plumbing.Hash
+	var refSpecs []config.RefSpec
+	for _, ref := range refs {
+		name := ref.Name().Short()
+		if !ref.Name().IsTag() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		tags = append(tags, RemoteTag{Name: name})
+		hashes = append(hashes, ref.Hash())
+		refSpecs = append(refSpecs, config.RefSpec(fmt.Sprintf("+refs/tags/%s:refs/tags/%s", name, name)))
+	}
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	err = gc.repo.FetchContext(ctx, &git.FetchOptions{RemoteName: "origin", RefSpecs: refSpecs, Tags: git.NoTags, Auth: gc.auth})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, fmt.Errorf("failed to fetch remote tags: %w", err)
+	}
+
+	for i := range tags {
+		obj, err := gc.repo.Object(plumbing.AnyObject, hashes[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tag %s: %w", tags[i].Name, err)
+		}
+		if tag, ok := obj.(*object.Tag); ok {
+			tags[i].Created = tag.Tagger.When
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+// DeleteRemoteTags deletes tags from origin in a single push, along with their local copies.
+func (gc *Client) DeleteRemoteTags(ctx context.Context, tags []string) error {
+	refNames := make([]plumbing.ReferenceName, 0, len(tags))
+	for _, tag := range tags {
+		refNames = append(refNames, plumbing.NewTagReferenceName(tag))
+	}
+	if err := gc.deleteRemoteRefs(ctx, refNames); err != nil {
+		return fmt.Errorf("failed to delete remote tags: %w", err)
+	}
+
+	for _, tag := range tags {
+		if err := gc.repo.DeleteTag(tag); err != nil && !errors.Is(err, git.ErrTagNotFound) {
+			return fmt.Errorf("failed to delete local tag %s: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+func (gc *Client) deleteRemoteRefs(ctx context.Context, refNames []plumbing.ReferenceName) error {
+	if len(refNames) == 0 {
+		return nil
+	}
+	refSpecs := make([]config.RefSpec, 0, len(refNames))
+	for _, name := range refNames {
+		refSpecs = append(refSpecs, config.RefSpec(":"+name.String()))
+	}
+	return gc.push(ctx, &git.PushOptions{RemoteName: "origin", RefSpecs: refSpecs, Auth: gc.auth})
+}
+
+func isNonFastForward(err error) bool {
+	if errors.Is(err, git.ErrForceNeeded) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "non-fast-forward") || strings.Contains(msg, "fetch first")
+}
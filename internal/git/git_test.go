@@ -10,7 +10,9 @@ import (
 	"crypto/x509"
 	"encoding/binary"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
@@ -21,7 +23,12 @@ import (
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gogitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"grafana-db-exporter/internal/signing"
 )
 
 func marshalOpenSSHED25519PrivateKey(privateKey ed25519.PrivateKey) ([]byte, error) {
@@ -202,7 +209,11 @@ func TestNew(t *testing.T) {
 				t.Fatalf("Failed to create initial commit: %v", err)
 			}
 
-			client, err := New(filepath.Join(tempDir, fmt.Sprintf("repo_%s", keyType)), mockRepo, sshKeyPath, "", knownHostsPath, true)
+			client, err := New(context.Background(), filepath.Join(tempDir, fmt.Sprintf("repo_%s", keyType)), mockRepo, AuthOptions{
+				SSHKeyPath:         sshKeyPath,
+				KnownHostsPath:     knownHostsPath,
+				AcceptUnknownHosts: true,
+			}, CloneOptions{BaseBranch: "master"})
 			if err != nil {
 				t.Fatalf("New() error = %v", err)
 			}
@@ -223,6 +234,216 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNewAuth(t *testing.T) {
+	tests := []struct {
+		name      string
+		remoteURL string
+		opts      AuthOptions
+		wantUser  string
+		wantNil   bool
+		wantErr   bool
+	}{
+		{
+			name:      "HTTPS with token",
+			remoteURL: "https://github.com/org/repo.git",
+			opts:      AuthOptions{Username: "x-access-token", Token: "secret"},
+			wantUser:  "x-access-token",
+		},
+		{
+			name:      "HTTPS without token",
+			remoteURL: "HTTPS://gitlab.example.com/group/repo.git",
+			wantNil:   true,
+		},
+		{
+			name:      "HTTPS ignores missing SSH key",
+			remoteURL: "http://gitea.local/org/repo.git",
+			opts:      AuthOptions{SSHKeyPath: "/nonexistent/key", Username: "gitlab-ci-token", Token: "secret"},
+			wantUser:  "gitlab-ci-token",
+		},
+		{
+			name:      "SSH requires a key",
+			remoteURL: "git@github.com:org/repo.git",
+			opts:      AuthOptions{SSHKeyPath: "/nonexistent/key", Token: "secret"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := newAuth(tt.remoteURL, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newAuth() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.wantNil {
+				if auth != nil {
+					t.Errorf("newAuth() = %v, want nil", auth)
+				}
+				return
+			}
+			basic, ok := auth.(*githttp.BasicAuth)
+			if !ok {
+				t.Fatalf("newAuth() = %T, want *http.BasicAuth", auth)
+			}
+			if basic.Username != tt.wantUser || basic.Password != tt.opts.Token {
+				t.Errorf("newAuth() = %s:%s, want %s:%s", basic.Username, basic.Password, tt.wantUser, tt.opts.Token)
+			}
+		})
+	}
+}
+
+func writeSSHCertificate(t *testing.T, path string, key []byte) {
+	t.Helper()
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to parse SSH key: %v", err)
+	}
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate CA key: %v", err)
+	}
+	caSigner, err := ssh.NewSignerFromKey(caKey)
+	if err != nil {
+		t.Fatalf("Failed to create CA signer: %v", err)
+	}
+
+	cert := &ssh.Certificate{
+		Key:             signer.PublicKey(),
+		CertType:        ssh.UserCert,
+		KeyId:           "grafana-db-exporter",
+		ValidPrincipals: []string{"git"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err := cert.SignCert(rand.Reader, caSigner); err != nil {
+		t.Fatalf("Failed to sign certificate: %v", err)
+	}
+	if err := os.WriteFile(path, ssh.MarshalAuthorizedKey(cert), 0644); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+}
+
+func TestNewSSHAuth(t *testing.T) {
+	tempDir := t.TempDir()
+	key, err := generateSSHKey(t, "ed25519")
+	if err != nil {
+		t.Fatalf("Failed to generate SSH key: %v", err)
+	}
+
+	keyPath := filepath.Join(tempDir, "id_ed25519")
+	if err := os.WriteFile(keyPath, key, 0600); err != nil {
+		t.Fatalf("Failed to write SSH key: %v", err)
+	}
+	plainKeyPath := filepath.Join(tempDir, "id_plain")
+	if err := os.WriteFile(plainKeyPath, key, 0600); err != nil {
+		t.Fatalf("Failed to write SSH key: %v", err)
+	}
+	writeSSHCertificate(t, keyPath+"-cert.pub", key)
+	explicitCertPath := filepath.Join(tempDir, "explicit-cert.pub")
+	writeSSHCertificate(t, explicitCertPath, key)
+
+	tests := []struct {
+		name     string
+		opts     AuthOptions
+		wantCert bool
+		wantErr  bool
+	}{
+		{name: "Key file", opts: AuthOptions{SSHKeyPath: plainKeyPath}},
+		{name: "Key from environment", opts: AuthOptions{SSHKeyPath: "/nonexistent/key", SSHKey: string(key)}},
+		{name: "Certificate next to key", opts: AuthOptions{SSHKeyPath: keyPath}, wantCert: true},
+		{name: "Explicit certificate", opts: AuthOptions{SSHKey: string(key), SSHCertPath: explicitCertPath}, wantCert: true},
+		{name: "Not a certificate", opts: AuthOptions{SSHKey: string(key), SSHCertPath: keyPath}, wantErr: true},
+		{name: "Invalid key", opts: AuthOptions{SSHKey: "not a key"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.AcceptUnknownHosts = true
+			auth, err := newSSHAuth("git", tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newSSHAuth() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			keys, ok := auth.(*gogitssh.PublicKeys)
+			if !ok {
+				t.Fatalf("newSSHAuth() = %T, want *ssh.PublicKeys", auth)
+			}
+			if _, isCert := keys.Signer.PublicKey().(*ssh.Certificate); isCert != tt.wantCert {
+				t.Errorf("newSSHAuth() certificate = %v, want %v", isCert, tt.wantCert)
+			}
+			if keys.HostKeyCallback == nil {
+				t.Errorf("newSSHAuth() did not set a host key callback")
+			}
+		})
+	}
+}
+
+func TestNewSSHAuth_Agent(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Failed to listen on agent socket: %v", err)
+	}
+	defer listener.Close()
+
+	keyring := agent.NewKeyring()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = agent.ServeAgent(keyring, conn)
+			}()
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", socket)
+
+	auth, err := newSSHAuth("deploy", AuthOptions{UseSSHAgent: true, AcceptUnknownHosts: true})
+	if err != nil {
+		t.Fatalf("newSSHAuth() error = %v", err)
+	}
+	callback, ok := auth.(*gogitssh.PublicKeysCallback)
+	if !ok {
+		t.Fatalf("newSSHAuth() = %T, want *ssh.PublicKeysCallback", auth)
+	}
+	if callback.User != "deploy" || callback.HostKeyCallback == nil {
+		t.Errorf("newSSHAuth() = %+v", callback)
+	}
+
+	t.Setenv("SSH_AUTH_SOCK", "")
+	if _, err := newSSHAuth("git", AuthOptions{UseSSHAgent: true, AcceptUnknownHosts: true}); err == nil {
+		t.Errorf("newSSHAuth() should return an error without SSH_AUTH_SOCK")
+	}
+}
+
+func TestSSHUser(t *testing.T) {
+	tests := []struct {
+		remoteURL string
+		want      string
+	}{
+		{remoteURL: "git@github.com:org/repo.git", want: "git"},
+		{remoteURL: "deploy@git.example.com:org/repo.git", want: "deploy"},
+		{remoteURL: "ssh://deploy@git.example.com:2222/org/repo.git", want: "deploy"},
+		{remoteURL: "ssh://git.example.com/org/repo.git", want: "git"},
+		{remoteURL: "git.example.com:org/repo.git", want: "git"},
+		{remoteURL: "git.example.com:team@org/repo.git", want: "git"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.remoteURL, func(t *testing.T) {
+			if got := sshUser(tt.remoteURL); got != tt.want {
+				t.Errorf("sshUser() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestClient_CheckoutNewBranch(t *testing.T) {
 	tempDir, err := os.MkdirTemp("", "git-test")
 	if err != nil {
@@ -341,7 +562,8 @@ func TestClient_CommitAll(t *testing.T) {
 		t.Fatalf("Failed to create test file: %v", err)
 	}
 
-	err = client.CommitAll(context.Background(), "testuser", "test@example.com")
+	message := "chore(grafana): update 1 dashboard\n\nExport-Run-ID: run-1"
+	err = client.CommitAll(context.Background(), message, CommitOptions{AuthorName: "testuser", AuthorEmail: "test@example.com"})
 	if err != nil {
 		t.Fatalf("CommitAll() error = %v", err)
 	}
@@ -360,8 +582,95 @@ func TestClient_CommitAll(t *testing.T) {
 		t.Errorf("Commit author mismatch. Got %s <%s>, want testuser <test@example.com>", commit.Author.Name, commit.Author.Email)
 	}
 
-	if commit.Message != "Update Grafana dashboards" {
-		t.Errorf("Commit message mismatch. Got %s, want 'Update Grafana dashboards'", commit.Message)
+	if commit.Message != message {
+		t.Errorf("Commit message mismatch. Got %q, want %q", commit.Message, message)
+	}
+}
+
+func TestClient_CommitAllSigned(t *testing.T) {
+	tempDir := t.TempDir()
+	repo, err := git.PlainInit(tempDir, false)
+	if err != nil {
+		t.Fatalf("Failed to init git repo: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte("test content"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	key, err := generateSSHKey(t, "ed25519")
+	if err != nil {
+		t.Fatalf("Failed to generate SSH key: %v", err)
+	}
+	signer, err := signing.NewSSH(key, "")
+	if err != nil {
+		t.Fatalf("NewSSH() error = %v", err)
+	}
+
+	client := &Client{repo: repo}
+	err = client.CommitAll(context.Background(), "Signed export", CommitOptions{
+		AuthorName:     "Grafana Exporter",
+		AuthorEmail:    "exporter@example.com",
+		CommitterName:  "CI Bot",
+		CommitterEmail: "ci@example.com",
+		Signer:         signer,
+	})
+	if err != nil {
+		t.Fatalf("CommitAll() error = %v", err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("Failed to get HEAD: %v", err)
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatalf("Failed to get commit object: %v", err)
+	}
+	if commit.Author.Email != "exporter@example.com" || commit.Committer.Name != "CI Bot" || commit.Committer.Email != "ci@example.com" {
+		t.Errorf("CommitAll() author = %v, committer = %v", commit.Author, commit.Committer)
+	}
+	if !strings.HasPrefix(commit.PGPSignature, "-----BEGIN SSH SIGNATURE-----") {
+		t.Errorf("CommitAll() signature = %q, want an SSH signature", commit.PGPSignature)
+	}
+}
+
+func TestClient_ChangedFiles(t *testing.T) {
+	originPath := initOrigin(t)
+	repo, repoPath := cloneOrigin(t, originPath)
+	client := &Client{repo: repo}
+
+	files, err := client.ChangedFiles(context.Background())
+	if err != nil {
+		t.Fatalf("ChangedFiles() error = %v", err)
+	}
+	if len(files) != 0 {
+		t.Errorf("ChangedFiles() = %v, want no changes in a fresh clone", files)
+	}
+
+	// Rewriting a file with identical content is not a change.
+	if err := os.WriteFile(filepath.Join(repoPath, "dummy.txt"), []byte("dummy content"), 0644); err != nil {
+		t.Fatalf("Failed to rewrite dummy.txt: %v", err)
+	}
+	files, err = client.ChangedFiles(context.Background())
+	if err != nil {
+		t.Fatalf("ChangedFiles() error = %v", err)
+	}
+	if len(files) != 0 {
+		t.Errorf("ChangedFiles() = %v, want no changes after rewriting identical content", files)
+	}
+
+	if err := os.WriteFile(filepath.Join(repoPath, "dummy.txt"), []byte("changed"), 0644); err != nil {
+		t.Fatalf("Failed to modify dummy.txt: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, "new.txt"), []byte("new"), 0644); err != nil {
+		t.Fatalf("Failed to write new.txt: %v", err)
+	}
+	files, err = client.ChangedFiles(context.Background())
+	if err != nil {
+		t.Fatalf("ChangedFiles() error = %v", err)
+	}
+	if strings.Join(files, ",") != "dummy.txt,new.txt" {
+		t.Errorf("ChangedFiles() = %v, want [dummy.txt new.txt]", files)
 	}
 }
 
@@ -404,3 +713,545 @@ func branchExists(t *testing.T, repo *git.Repository, branchName string) bool {
 	}
 	return exists
 }
+
+func initOrigin(t *testing.T) string {
+	t.Helper()
+	tempDir := t.TempDir()
+
+	seedPath := filepath.Join(tempDir, "seed")
+	seed, err := git.PlainInitWithOptions(seedPath, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName("main")},
+	})
+	if err != nil {
+		t.Fatalf("Failed to init seed repo: %v", err)
+	}
+	commitFile(t, seed, seedPath, "dummy.txt", "dummy content")
+
+	originPath := filepath.Join(tempDir, "origin.git")
+	if _, err := git.PlainClone(originPath, true, &git.CloneOptions{URL: seedPath}); err != nil {
+		t.Fatalf("Failed to create origin repo: %v", err)
+	}
+	return originPath
+}
+
+func cloneOrigin(t *testing.T, originPath string) (*git.Repository, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "clone")
+	repo, err := git.PlainClone(path, false, &git.CloneOptions{URL: originPath})
+	if err != nil {
+		t.Fatalf("Failed to clone origin: %v", err)
+	}
+	return repo, path
+}
+
+func commitFile(t *testing.T, repo *git.Repository, repoPath, name, content string) plumbing.Hash {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(repoPath, name), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	if _, err := w.Add(name); err != nil {
+		t.Fatalf("Failed to add %s: %v", name, err)
+	}
+	hash, err := w.Commit("Update "+name, &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit %s: %v", name, err)
+	}
+	return hash
+}
+
+func TestNew_ReuseExistingClone(t *testing.T) {
+	originPath := initOrigin(t)
+	key, err := generateSSHKey(t, "ed25519")
+	if err != nil {
+		t.Fatalf("Failed to generate SSH key: %v", err)
+	}
+	authOpts := AuthOptions{SSHKey: string(key), AcceptUnknownHosts: true}
+	clonePath := filepath.Join(t.TempDir(), "clone")
+
+	client, err := New(context.Background(), clonePath, originPath, authOpts, CloneOptions{BaseBranch: "main"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := client.CheckoutNewBranch(context.Background(), "main", "stale-export"); err != nil {
+		t.Fatalf("CheckoutNewBranch() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(clonePath, "dummy.txt"), []byte("local edit"), 0644); err != nil {
+		t.Fatalf("Failed to modify dummy.txt: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(clonePath, "leftover.json"), []byte("{}"), 0644); err != nil {
+		t.Fatalf("Failed to write leftover.json: %v", err)
+	}
+
+	otherRepo, otherPath := cloneOrigin(t, originPath)
+	otherCommit := commitFile(t, otherRepo, otherPath, "other.txt", "other change")
+	if err := (&Client{repo: otherRepo}).Push(context.Background(), "main"); err != nil {
+		t.Fatalf("Push() from other clone error = %v", err)
+	}
+
+	reused, err := New(context.Background(), clonePath, originPath, authOpts, CloneOptions{BaseBranch: "main", Cleanup: true})
+	if err != nil {
+		t.Fatalf("New() reusing clone error = %v", err)
+	}
+	head, err := reused.repo.Head()
+	if err != nil {
+		t.Fatalf("Failed to get HEAD: %v", err)
+	}
+	if head.Name().Short() != "main" || head.Hash() != otherCommit {
+		t.Errorf("New() HEAD = %s at %s, want main at %s", head.Name().Short(), head.Hash(), otherCommit)
+	}
+	if content, _ := os.ReadFile(filepath.Join(clonePath, "dummy.txt")); string(content) != "dummy content" {
+		t.Errorf("New() kept local edit: %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(clonePath, "leftover.json")); !os.IsNotExist(err) {
+		t.Errorf("New() with cleanup kept untracked file")
+	}
+	if branchExists(t, reused.repo, "stale-export") {
+		t.Errorf("New() with cleanup kept stale branch")
+	}
+
+	if _, err := New(context.Background(), clonePath, "/other/remote.git", authOpts, CloneOptions{BaseBranch: "main"}); err == nil {
+		t.Errorf("New() should return an error for a clone of a different remote")
+	}
+}
+
+func TestNew_ShallowSingleBranch(t *testing.T) {
+	originPath := initOrigin(t)
+	otherRepo, otherPath := cloneOrigin(t, originPath)
+	other := &Client{repo: otherRepo}
+	commitFile(t, otherRepo, otherPath, "history.txt", "second commit")
+	commitFile(t, otherRepo, otherPath, "history.txt", "third commit")
+	if err := other.Push(context.Background(), "main"); err != nil {
+		t.Fatalf("Push() from other clone error = %v", err)
+	}
+	if _, err := other.CheckoutNewBranch(context.Background(), "main", "feature-"); err != nil {
+		t.Fatalf("CheckoutNewBranch() error = %v", err)
+	}
+	head, err := otherRepo.Head()
+	if err != nil {
+		t.Fatalf("Failed to get HEAD: %v", err)
+	}
+	if err := other.Push(context.Background(), head.Name().Short()); err != nil {
+		t.Fatalf("Push() feature branch error = %v", err)
+	}
+
+	key, err := generateSSHKey(t, "ed25519")
+	if err != nil {
+		t.Fatalf("Failed to generate SSH key: %v", err)
+	}
+	authOpts := AuthOptions{SSHKey: string(key), AcceptUnknownHosts: true}
+	clonePath := filepath.Join(t.TempDir(), "clone")
+
+	client, err := New(context.Background(), clonePath, originPath, authOpts, CloneOptions{BaseBranch: "main", Depth: 1, SingleBranch: true})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	shallow, err := client.repo.Storer.Shallow()
+	if err != nil {
+		t.Fatalf("Failed to read shallow commits: %v", err)
+	}
+	if len(shallow) != 1 {
+		t.Errorf("New() shallow commits = %v, want one", shallow)
+	}
+	if _, err := client.repo.Reference(plumbing.NewRemoteReferenceName("origin", head.Name().Short()), true); err == nil {
+		t.Errorf("New() with single branch fetched %s", head.Name().Short())
+	}
+
+	branch, err := client.CheckoutNewBranch(context.Background(), "main", "export-")
+	if err != nil {
+		t.Fatalf("CheckoutNewBranch() error = %v", err)
+	}
+	exportCommit := commitFile(t, client.repo, clonePath, "export.txt", "export")
+	if err := client.Push(context.Background(), branch); err != nil {
+		t.Fatalf("Push() from shallow clone error = %v", err)
+	}
+
+	origin, err := git.PlainOpen(originPath)
+	if err != nil {
+		t.Fatalf("Failed to open origin: %v", err)
+	}
+	ref, err := origin.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil || ref.Hash() != exportCommit {
+		t.Errorf("Origin %s = %v (%v), want %s", branch, ref, err, exportCommit)
+	}
+}
+
+func TestNew_CancelledContext(t *testing.T) {
+	originPath := initOrigin(t)
+	key, err := generateSSHKey(t, "ed25519")
+	if err != nil {
+		t.Fatalf("Failed to generate SSH key: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	clonePath := filepath.Join(t.TempDir(), "clone")
+	_, err = New(ctx, clonePath, originPath, AuthOptions{SSHKey: string(key), AcceptUnknownHosts: true}, CloneOptions{BaseBranch: "main"})
+	if err == nil {
+		t.Fatalf("New() should return an error for a cancelled context")
+	}
+	if _, err := git.PlainOpen(clonePath); err == nil {
+		t.Errorf("New() left a partial clone behind")
+	}
+}
+
+func TestClient_PushNonFastForward(t *testing.T) {
+	originPath := initOrigin(t)
+
+	otherRepo, otherPath := cloneOrigin(t, originPath)
+	otherCommit := commitFile(t, otherRepo, otherPath, "other.txt", "other change")
+
+	repo, repoPath := cloneOrigin(t, originPath)
+	client := &Client{repo: repo}
+	if err := client.CheckoutBranch(context.Background(), "main"); err != nil {
+		t.Fatalf("CheckoutBranch() error = %v", err)
+	}
+	commitFile(t, repo, repoPath, "export.txt", "export")
+
+	if err := (&Client{repo: otherRepo}).Push(context.Background(), "main"); err != nil {
+		t.Fatalf("Push() from other clone error = %v", err)
+	}
+
+	err := client.Push(context.Background(), "main")
+	if !errors.Is(err, ErrNonFastForward) {
+		t.Fatalf("Push() error = %v, want ErrNonFastForward", err)
+	}
+
+	if err := client.ResetToRemote(context.Background(), "main"); err != nil {
+		t.Fatalf("ResetToRemote() error = %v", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("Failed to get HEAD: %v", err)
+	}
+	if head.Hash() != otherCommit {
+		t.Errorf("ResetToRemote() HEAD = %s, want %s", head.Hash(), otherCommit)
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, "other.txt")); err != nil {
+		t.Errorf("ResetToRemote() did not update the worktree: %v", err)
+	}
+
+	commitFile(t, repo, repoPath, "export.txt", "export")
+	if err := client.Push(context.Background(), "main"); err != nil {
+		t.Errorf("Push() after reset error = %v", err)
+	}
+}
+
+func TestClient_PushRemote(t *testing.T) {
+	originPath := initOrigin(t)
+	mirrorPath := initOrigin(t)
+	key, err := generateSSHKey(t, "ed25519")
+	if err != nil {
+		t.Fatalf("Failed to generate SSH key: %v", err)
+	}
+	authOpts := AuthOptions{SSHKey: string(key), AcceptUnknownHosts: true}
+
+	repo, repoPath := cloneOrigin(t, originPath)
+	client := &Client{repo: repo}
+	if err := client.PushRemote(context.Background(), "dr", "main", false); err == nil {
+		t.Errorf("PushRemote() should return an error for an unknown remote")
+	}
+
+	if err := client.AddRemote("dr", "/previous/url.git", authOpts); err != nil {
+		t.Fatalf("AddRemote() error = %v", err)
+	}
+	if err := client.AddRemote("dr", mirrorPath, authOpts); err != nil {
+		t.Fatalf("AddRemote() replacing remote error = %v", err)
+	}
+
+	branch, err := client.CheckoutNewBranch(context.Background(), "main", "export-")
+	if err != nil {
+		t.Fatalf("CheckoutNewBranch() error = %v", err)
+	}
+	exportCommit := commitFile(t, repo, repoPath, "export.txt", "export")
+	if err := client.PushRemote(context.Background(), "dr", branch, false); err != nil {
+		t.Fatalf("PushRemote() error = %v", err)
+	}
+
+	mirrorRepo, mirrorClonePath := cloneOrigin(t, mirrorPath)
+	commitFile(t, mirrorRepo, mirrorClonePath, "diverged.txt", "mirror only")
+	if err := (&Client{repo: mirrorRepo}).Push(context.Background(), "main"); err != nil {
+		t.Fatalf("Push() to mirror error = %v", err)
+	}
+	if err := client.PushRemote(context.Background(), "dr", "main", false); !errors.Is(err, ErrNonFastForward) {
+		t.Errorf("PushRemote() error = %v, want ErrNonFastForward", err)
+	}
+	if err := client.PushRemote(context.Background(), "dr", "main", true); err != nil {
+		t.Errorf("PushRemote() with force error = %v", err)
+	}
+
+	mirror, err := git.PlainOpen(mirrorPath)
+	if err != nil {
+		t.Fatalf("Failed to open mirror: %v", err)
+	}
+	ref, err := mirror.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil || ref.Hash() != exportCommit {
+		t.Errorf("Mirror %s = %v (%v), want %s", branch, ref, err, exportCommit)
+	}
+}
+
+func TestClient_ConflictingFiles(t *testing.T) {
+	originPath := initOrigin(t)
+
+	otherRepo, otherPath := cloneOrigin(t, originPath)
+	commitFile(t, otherRepo, otherPath, "edited-in-git.json", "manual edit")
+	commitFile(t, otherRepo, otherPath, "unrelated.txt", "unrelated")
+
+	repo, repoPath := cloneOrigin(t, originPath)
+	client := &Client{repo: repo}
+	commitFile(t, repo, repoPath, "edited-in-git.json", "grafana export")
+
+	if err := (&Client{repo: otherRepo}).Push(context.Background(), "main"); err != nil {
+		t.Fatalf("Push() from other clone error = %v", err)
+	}
+
+	conflicts, err := client.ConflictingFiles(context.Background(), "main")
+	if err != nil {
+		t.Fatalf("ConflictingFiles() error = %v", err)
+	}
+	if strings.Join(conflicts, ",") != "edited-in-git.json" {
+		t.Errorf("ConflictingFiles() = %v, want [edited-in-git.json]", conflicts)
+	}
+}
+
+func TestClient_ListAndDeleteRemoteBranches(t *testing.T) {
+	originPath := initOrigin(t)
+	otherRepo, otherPath := cloneOrigin(t, originPath)
+	other := &Client{repo: otherRepo}
+
+	for _, branch := range []string{"export-merged", "export-open", "feature"} {
+		if err := other.CheckoutBranch(context.Background(), "main"); err != nil {
+			t.Fatalf("CheckoutBranch() error = %v", err)
+		}
+		if _, err := other.CheckoutNewBranch(context.Background(), "main", branch); err != nil {
+			t.Fatalf("CheckoutNewBranch() error = %v", err)
+		}
+		head, err := otherRepo.Head()
+		if err != nil {
+			t.Fatalf("Failed to get HEAD: %v", err)
+		}
+		commitFile(t, otherRepo, otherPath, branch+".txt", branch)
+		if err := other.Push(context.Background(), head.Name().Short()); err != nil {
+			t.Fatalf("Push() error = %v", err)
+		}
+		if branch == "export-merged" {
+			// Fast-forward main to the export branch.
+			if err := other.CheckoutBranch(context.Background(), "main"); err != nil {
+				t.Fatalf("CheckoutBranch() error = %v", err)
+			}
+			w, _ := otherRepo.Worktree()
+			exportHead, _ := otherRepo.Reference(head.Name(), true)
+			if err := w.Reset(&git.ResetOptions{Commit: exportHead.Hash(), Mode: git.HardReset}); err != nil {
+				t.Fatalf("Failed to merge export branch: %v", err)
+			}
+			if err := other.Push(context.Background(), "main"); err != nil {
+				t.Fatalf("Push() main error = %v", err)
+			}
+		}
+	}
+
+	repo, _ := cloneOrigin(t, originPath)
+	client := &Client{repo: repo}
+	branches, err := client.ListRemoteBranches(context.Background(), "export-", "main", true)
+	if err != nil {
+		t.Fatalf("ListRemoteBranches() error = %v", err)
+	}
+	if len(branches) != 2 {
+		t.Fatalf("ListRemoteBranches() = %+v, want two export branches", branches)
+	}
+	for _, b := range branches {
+		wantMerged := strings.HasPrefix(b.Name, "export-merged")
+		if b.Merged != wantMerged {
+			t.Errorf("ListRemoteBranches() %s merged = %v, want %v", b.Name, b.Merged, wantMerged)
+		}
+	}
+
+	if err := client.DeleteRemoteBranches(context.Background(), []string{branches[0].Name}); err != nil {
+		t.Fatalf("DeleteRemoteBranches() error = %v", err)
+	}
+	remaining, err := client.ListRemoteBranches(context.Background(), "export-", "main", false)
+	if err != nil {
+		t.Fatalf("ListRemoteBranches() error = %v", err)
+	}
+	if len(remaining) != 1 || remaining[0].Name != branches[1].Name {
+		t.Errorf("ListRemoteBranches() after delete = %+v", remaining)
+	}
+}
+
+func TestClient_CreateListAndDeleteTags(t *testing.T) {
+	originPath := initOrigin(t)
+	otherRepo, _ := cloneOrigin(t, originPath)
+	other := &Client{repo: otherRepo}
+
+	opts := CommitOptions{AuthorName: "exporter", AuthorEmail: "exporter@example.com"}
+	if err := other.CreateTag("export-1", "Grafana export\n\nDashboards: 3\n", opts); err != nil {
+		t.Fatalf("CreateTag() error = %v", err)
+	}
+	head, _ := otherRepo.Head()
+	for _, name := range []string{"export-light", "release"} {
+		if _, err := otherRepo.CreateTag(name, head.Hash(), nil); err != nil {
+			t.Fatalf("Failed to create lightweight tag: %v", err)
+		}
+	}
+	for _, name := range []string{"export-1", "export-light", "release"} {
+		if err := other.PushTag(context.Background(), "origin", name); err != nil {
+			t.Fatalf("PushTag() error = %v", err)
+		}
+	}
+	if err := other.PushTag(context.Background(), "missing", "export-1"); err == nil {
+		t.Error("PushTag() to unknown remote expected error")
+	}
+
+	repo, _ := cloneOrigin(t, originPath)
+	client := &Client{repo: repo}
+	tags, err := client.ListRemoteTags(context.Background(), "export-")
+	if err != nil {
+		t.Fatalf("ListRemoteTags() error = %v", err)
+	}
+	if len(tags) != 2 || tags[0].Name != "export-1" || tags[1].Name != "export-light" {
+		t.Fatalf("ListRemoteTags() = %+v, want export-1 and export-light", tags)
+	}
+	if tags[0].Created.IsZero() || !tags[1].Created.IsZero() {
+		t.Errorf("ListRemoteTags() created = %v, %v, want only the annotated tag dated", tags[0].Created, tags[1].Created)
+	}
+
+	if err := client.DeleteRemoteTags(context.Background(), []string{"export-1"}); err != nil {
+		t.Fatalf("DeleteRemoteTags() error = %v", err)
+	}
+	if _, err := repo.Tag("export-1"); !errors.Is(err, git.ErrTagNotFound) {
+		t.Errorf("local tag after DeleteRemoteTags() error = %v, want ErrTagNotFound", err)
+	}
+	remaining, err := client.ListRemoteTags(context.Background(), "export-")
+	if err != nil {
+		t.Fatalf("ListRemoteTags() error = %v", err)
+	}
+	if len(remaining) != 1 || remaining[0].Name != "export-light" {
+		t.Errorf("ListRemoteTags() after delete = %+v", remaining)
+	}
+}
+
+func TestClient_MatchesRemote(t *testing.T) {
+	originPath := initOrigin(t)
+	syncBranch := "grafana-db-exporter/sync"
+
+	firstRepo, firstPath := cloneOrigin(t, originPath)
+	first := &Client{repo: firstRepo}
+	if err := first.CheckoutSyncBranch(context.Background(), "main", syncBranch); err != nil {
+		t.Fatalf("CheckoutSyncBranch() error = %v", err)
+	}
+	commitFile(t, firstRepo, firstPath, "export.txt", "export")
+	if matches, err := first.MatchesRemote(syncBranch); err != nil || matches {
+		t.Fatalf("MatchesRemote() before first push = %v, %v, want false", matches, err)
+	}
+	if err := first.PushWithLease(context.Background(), syncBranch); err != nil {
+		t.Fatalf("PushWithLease() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		expected bool
+	}{
+		{name: "Same export", content: "export", expected: true},
+		{name: "Changed export", content: "changed export", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, path := cloneOrigin(t, originPath)
+			client := &Client{repo: repo}
+			if err := client.CheckoutSyncBranch(context.Background(), "main", syncBranch); err != nil {
+				t.Fatalf("CheckoutSyncBranch() error = %v", err)
+			}
+			commitFile(t, repo, path, "export.txt", tt.content)
+
+			matches, err := client.MatchesRemote(syncBranch)
+			if err != nil {
+				t.Fatalf("MatchesRemote() error = %v", err)
+			}
+			if matches != tt.expected {
+				t.Errorf("MatchesRemote() = %v, want %v", matches, tt.expected)
+			}
+		})
+	}
+}
+
+func TestClient_PushWithLease(t *testing.T) {
+	originPath := initOrigin(t)
+	syncBranch := "grafana-db-exporter/sync"
+
+	firstRepo, firstPath := cloneOrigin(t, originPath)
+	first := &Client{repo: firstRepo}
+	if err := first.CheckoutSyncBranch(context.Background(), "main", syncBranch); err != nil {
+		t.Fatalf("CheckoutSyncBranch() error = %v", err)
+	}
+	commitFile(t, firstRepo, firstPath, "export.txt", "first export")
+	if err := first.PushWithLease(context.Background(), syncBranch); err != nil {
+		t.Fatalf("PushWithLease() creating branch error = %v", err)
+	}
+
+	secondRepo, secondPath := cloneOrigin(t, originPath)
+	second := &Client{repo: secondRepo}
+	if err := second.CheckoutSyncBranch(context.Background(), "main", syncBranch); err != nil {
+		t.Fatalf("CheckoutSyncBranch() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(secondPath, "export.txt")); !os.IsNotExist(err) {
+		t.Errorf("CheckoutSyncBranch() did not reset the sync branch to the base branch")
+	}
+	secondCommit := commitFile(t, secondRepo, secondPath, "export.txt", "second export")
+	if err := second.PushWithLease(context.Background(), syncBranch); err != nil {
+		t.Fatalf("PushWithLease() overwriting branch error = %v", err)
+	}
+
+	commitFile(t, firstRepo, firstPath, "export.txt", "stale export")
+	err := first.PushWithLease(context.Background(), syncBranch)
+	if !errors.Is(err, ErrNonFastForward) {
+		t.Fatalf("PushWithLease() with stale lease error = %v, want ErrNonFastForward", err)
+	}
+
+	origin, err := git.PlainOpen(originPath)
+	if err != nil {
+		t.Fatalf("Failed to open origin: %v", err)
+	}
+	ref, err := origin.Reference(plumbing.NewBranchReferenceName(syncBranch), true)
+	if err != nil {
+		t.Fatalf("Failed to resolve sync branch on origin: %v", err)
+	}
+	if ref.Hash() != secondCommit {
+		t.Errorf("Origin sync branch = %s, want %s", ref.Hash(), secondCommit)
+	}
+}
+
+func TestParseRemoteURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		wantHost string
+		wantPath string
+		wantErr  bool
+	}{
+		{url: "git@github.com:org/repo.git", wantHost: "github.com", wantPath: "org/repo"},
+		{url: "ssh://git@gitlab.example.com:2222/group/sub/repo.git", wantHost: "gitlab.example.com", wantPath: "group/sub/repo"},
+		{url: "https://github.example.com/org/repo", wantHost: "github.example.com", wantPath: "org/repo"},
+		{url: "/tmp/repo", wantErr: true},
+		{url: "git@github.com:", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			host, path, err := ParseRemoteURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRemoteURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if host != tt.wantHost || path != tt.wantPath {
+				t.Errorf("ParseRemoteURL() = %s, %s, want %s, %s", host, path, tt.wantHost, tt.wantPath)
+			}
+		})
+	}
+}
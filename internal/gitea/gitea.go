@@ -0,0 +1,214 @@
+package gitea
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"grafana-db-exporter/internal/forge"
+	"grafana-db-exporter/internal/logger"
+)
+
+const draftPrefix = "WIP: "
+
+type Client struct {
+	owner string
+	repo  string
+	api   *forge.Client
+}
+
+type PullRequestOptions struct {
+	Head      string
+	Base      string
+	Title     string
+	Body      string
+	Labels    []string
+	Assignees []string
+	Reviewers []string
+	Draft     bool
+}
+
+type PullRequest struct {
+	Number int    `json:"number"`
+	URL    string `json:"html_url"`
+	Head   struct {
+		Ref    string `json:"ref"`
+		RepoID int64  `json:"repo_id"`
+	} `json:"head"`
+	Base struct {
+		Ref    string `json:"ref"`
+		RepoID int64  `json:"repo_id"`
+	} `json:"base"`
+}
+
+type label struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func New(apiURL, token, repoPath string) (*Client, error) {
+	if _, err := url.ParseRequestURI(apiURL); err != nil {
+		return nil, fmt.Errorf("invalid Gitea API URL: %w", err)
+	}
+
+	owner, repo, ok := strings.Cut(repoPath, "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return nil, fmt.Errorf("invalid Gitea repository %q, expected owner/repo", repoPath)
+	}
+
+	return &Client{
+		owner: owner,
+		repo:  repo,
+		api:   forge.NewClient(apiURL, map[string]string{"Authorization": "token " + token}),
+	}, nil
+}
+
+// EnsurePullRequest updates the open pull request from opts.Head into opts.Base, or creates one if none
+// exists. It reports whether a new pull request was created.
+func (c *Client) EnsurePullRequest(ctx context.Context, opts PullRequestOptions) (*PullRequest, bool, error) {
+	existing, err := c.findPullRequest(ctx, opts.Head, opts.Base)
+	if err != nil {
+		return nil, false, err
+	}
+
+	title := opts.Title
+	if opts.Draft && !strings.HasPrefix(title, draftPrefix) {
+		title = draftPrefix + title
+	}
+
+	var pr *PullRequest
+	created := existing == nil
+	if created {
+		logger.Log.Debug().Str("head", opts.Head).Str("base", opts.Base).Msg("Creating Gitea pull request")
+		fields := map[string]interface{}{
+			"title": title,
+			"body":  opts.Body,
+			"head":  opts.Head,
+			"base":  opts.Base,
+		}
+		if len(opts.Assignees) > 0 {
+			fields["assignees"] = opts.Assignees
+		}
+
+		pr = &PullRequest{}
+		if err := c.api.Do(ctx, http.MethodPost, c.repoPath("pulls"), fields, pr); err != nil {
+			return nil, false, fmt.Errorf("failed to create pull request: %w", err)
+		}
+	} else {
+		logger.Log.Debug().Int("number", existing.Number).Msg("Updating Gitea pull request")
+		pr = existing
+		err := c.api.Do(ctx, http.MethodPatch, c.repoPath(fmt.Sprintf("pulls/%d", pr.Number)), map[string]interface{}{
+			"title": title,
+			"body":  opts.Body,
+		}, nil)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to update pull request #%d: %w", pr.Number, err)
+		}
+	}
+
+	if len(opts.Labels) > 0 {
+		ids, err := c.labelIDs(ctx, opts.Labels)
+		if err != nil {
+			return nil, false, err
+		}
+		err = c.api.Do(ctx, http.MethodPost, c.repoPath(fmt.Sprintf("issues/%d/labels", pr.Number)), map[string]interface{}{
+			"labels": ids,
+		}, nil)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to add labels to pull request #%d: %w", pr.Number, err)
+		}
+	}
+
+	if len(opts.Reviewers) > 0 {
+		err := c.api.Do(ctx, http.MethodPost, c.repoPath(fmt.Sprintf("pulls/%d/requested_reviewers", pr.Number)), map[string]interface{}{
+			"reviewers": opts.Reviewers,
+		}, nil)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to request reviewers for pull request #%d: %w", pr.Number, err)
+		}
+	}
+
+	return pr, created, nil
+}
+
+func (c *Client) findPullRequest(ctx context.Context, head, base string) (*PullRequest, error) {
+	for page := 1; ; page++ {
+		var prs []PullRequest
+		path := fmt.Sprintf("%s?state=open&limit=50&page=%d", c.repoPath("pulls"), page)
+		if err := c.api.Do(ctx, http.MethodGet, path, nil, &prs); err != nil {
+			return nil, fmt.Errorf("failed to list pull requests: %w", err)
+		}
+		for i := range prs {
+			if prs[i].Head.Ref == head && prs[i].Base.Ref == base {
+				return &prs[i], nil
+			}
+		}
+		if len(prs) < 50 {
+			return nil, nil
+		}
+	}
+}
+
+// CloseSupersededPullRequests closes the open pull requests into base from branches of this repository
+// that start with prefix, except the one from keep. It returns the numbers of the closed pull requests.
+func (c *Client) CloseSupersededPullRequests(ctx context.Context, prefix, base, keep string) ([]int, error) {
+	// Collect all pages first, as closing pull requests would shift the later pages.
+	var superseded []int
+	for page := 1; ; page++ {
+		var prs []PullRequest
+		path := fmt.Sprintf("%s?state=open&limit=50&page=%d", c.repoPath("pulls"), page)
+		if err := c.api.Do(ctx, http.MethodGet, path, nil, &prs); err != nil {
+			return nil, fmt.Errorf("failed to list pull requests: %w", err)
+		}
+		for _, pr := range prs {
+			if pr.Base.Ref == base && pr.Head.RepoID == pr.Base.RepoID && pr.Head.Ref != keep && strings.HasPrefix(pr.Head.Ref, prefix) {
+				superseded = append(superseded, pr.Number)
+			}
+		}
+		if len(prs) < 50 {
+			break
+		}
+	}
+
+	var closed []int
+	for _, number := range superseded {
+		logger.Log.Debug().Int("number", number).Msg("Closing superseded Gitea pull request")
+		err := c.api.Do(ctx, http.MethodPatch, c.repoPath(fmt.Sprintf("pulls/%d", number)), map[string]interface{}{
+			"state": "closed",
+		}, nil)
+		if err != nil {
+			return closed, fmt.Errorf("failed to close pull request #%d: %w", number, err)
+		}
+		closed = append(closed, number)
+	}
+	return closed, nil
+}
+
+// labelIDs resolves label names to IDs, as the Gitea API does not accept names.
+func (c *Client) labelIDs(ctx context.Context, names []string) ([]int, error) {
+	var labels []label
+	if err := c.api.Do(ctx, http.MethodGet, c.repoPath("labels?limit=100"), nil, &labels); err != nil {
+		return nil, fmt.Errorf("failed to list labels: %w", err)
+	}
+
+	byName := make(map[string]int, len(labels))
+	for _, l := range labels {
+		byName[l.Name] = l.ID
+	}
+
+	var ids []int
+	for _, name := range names {
+		id, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("label %q does not exist in %s/%s", name, c.owner, c.repo)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (c *Client) repoPath(path string) string {
+	return fmt.Sprintf("/repos/%s/%s/%s", url.PathEscape(c.owner), url.PathEscape(c.repo), path)
+}
@@ -0,0 +1,141 @@
+package gitea
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"grafana-db-exporter/internal/forge/forgetest"
+)
+
+func newStubServer(t *testing.T, existing string) *forgetest.Server {
+	t.Helper()
+	return forgetest.NewServer(t, "Authorization", "token test-token", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/repos/org/dashboards/pulls":
+			_, _ = w.Write([]byte(existing))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/repos/org/dashboards/labels":
+			_, _ = w.Write([]byte(`[{"id":1,"name":"grafana"},{"id":2,"name":"bug"}]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/repos/org/dashboards/pulls":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"number":9,"html_url":"https://gitea.example.com/org/dashboards/pulls/9"}`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/api/v1/repos/org/dashboards/pulls/4",
+			r.Method == http.MethodPost && r.URL.Path == "/api/v1/repos/org/dashboards/issues/9/labels",
+			r.Method == http.MethodPost && r.URL.Path == "/api/v1/repos/org/dashboards/issues/4/labels",
+			r.Method == http.MethodPost && r.URL.Path == "/api/v1/repos/org/dashboards/pulls/9/requested_reviewers",
+			r.Method == http.MethodPost && r.URL.Path == "/api/v1/repos/org/dashboards/pulls/4/requested_reviewers":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"not found"}`))
+		}
+	})
+}
+
+func TestClient_EnsurePullRequest_Create(t *testing.T) {
+	server := newStubServer(t, `[{"number":3,"head":{"ref":"other"},"base":{"ref":"main"}}]`)
+	client, err := New(server.URL+"/api/v1", "test-token", "org/dashboards")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	pr, created, err := client.EnsurePullRequest(context.Background(), PullRequestOptions{
+		Head:      "grafana-db-exporter/sync",
+		Base:      "main",
+		Title:     "Update Grafana dashboards",
+		Labels:    []string{"grafana"},
+		Assignees: []string{"alice"},
+		Reviewers: []string{"bob"},
+		Draft:     true,
+	})
+	if err != nil {
+		t.Fatalf("EnsurePullRequest() error = %v", err)
+	}
+	if !created || pr.Number != 9 {
+		t.Errorf("EnsurePullRequest() = %+v, created %v, want #9 created", pr, created)
+	}
+
+	create := server.Request(http.MethodPost, "/api/v1/repos/org/dashboards/pulls")
+	if create == nil || create.Body["title"] != "WIP: Update Grafana dashboards" || create.Body["head"] != "grafana-db-exporter/sync" {
+		t.Errorf("Create request = %+v", create)
+	}
+	labels := server.Request(http.MethodPost, "/api/v1/repos/org/dashboards/issues/9/labels")
+	ids, _ := labels.Body["labels"].([]interface{})
+	if len(ids) != 1 || ids[0] != float64(1) {
+		t.Errorf("Labels body = %v", labels.Body)
+	}
+	if server.Request(http.MethodPost, "/api/v1/repos/org/dashboards/pulls/9/requested_reviewers") == nil {
+		t.Errorf("EnsurePullRequest() did not request reviewers")
+	}
+}
+
+func TestClient_EnsurePullRequest_Update(t *testing.T) {
+	server := newStubServer(t, `[{"number":4,"head":{"ref":"grafana-db-exporter/sync"},"base":{"ref":"main"}}]`)
+	client, err := New(server.URL+"/api/v1", "test-token", "org/dashboards")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	pr, created, err := client.EnsurePullRequest(context.Background(), PullRequestOptions{
+		Head:      "grafana-db-exporter/sync",
+		Base:      "main",
+		Title:     "Update Grafana dashboards",
+		Body:      "body",
+		Reviewers: []string{"bob"},
+	})
+	if err != nil {
+		t.Fatalf("EnsurePullRequest() error = %v", err)
+	}
+	if created || pr.Number != 4 {
+		t.Errorf("EnsurePullRequest() = %+v, created %v, want existing #4", pr, created)
+	}
+	if server.Request(http.MethodPost, "/api/v1/repos/org/dashboards/pulls") != nil {
+		t.Errorf("EnsurePullRequest() created a duplicate pull request")
+	}
+	if update := server.Request(http.MethodPatch, "/api/v1/repos/org/dashboards/pulls/4"); update == nil || update.Body["body"] != "body" {
+		t.Errorf("Update request = %+v", update)
+	}
+	if server.Request(http.MethodPost, "/api/v1/repos/org/dashboards/pulls/4/requested_reviewers") == nil {
+		t.Errorf("EnsurePullRequest() did not request reviewers on update")
+	}
+}
+
+func TestClient_EnsurePullRequest_UnknownLabel(t *testing.T) {
+	server := newStubServer(t, `[]`)
+	client, err := New(server.URL+"/api/v1", "test-token", "org/dashboards")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if _, _, err := client.EnsurePullRequest(context.Background(), PullRequestOptions{
+		Head:   "b",
+		Base:   "main",
+		Labels: []string{"missing"},
+	}); err == nil {
+		t.Errorf("EnsurePullRequest() should return an error for unknown labels")
+	}
+}
+
+func TestClient_CloseSupersededPullRequests(t *testing.T) {
+	server := newStubServer(t, `[
+		{"number":4,"head":{"ref":"grafana-db-exporter-20240101000000","repo_id":1},"base":{"ref":"main","repo_id":1}},
+		{"number":5,"head":{"ref":"grafana-db-exporter-20240102000000","repo_id":1},"base":{"ref":"main","repo_id":1}},
+		{"number":6,"head":{"ref":"grafana-db-exporter-20240101000000","repo_id":1},"base":{"ref":"release","repo_id":1}},
+		{"number":7,"head":{"ref":"grafana-db-exporter-20240101000000","repo_id":2},"base":{"ref":"main","repo_id":1}}
+	]`)
+	client, err := New(server.URL+"/api/v1", "test-token", "org/dashboards")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	closed, err := client.CloseSupersededPullRequests(context.Background(), "grafana-db-exporter-", "main", "grafana-db-exporter-20240102000000")
+	if err != nil {
+		t.Fatalf("CloseSupersededPullRequests() error = %v", err)
+	}
+	if len(closed) != 1 || closed[0] != 4 {
+		t.Errorf("CloseSupersededPullRequests() = %v, want [4]", closed)
+	}
+	if update := server.Request(http.MethodPatch, "/api/v1/repos/org/dashboards/pulls/4"); update == nil || update.Body["state"] != "closed" {
+		t.Errorf("Close request = %+v", update)
+	}
+}
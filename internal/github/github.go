@@ -0,0 +1,197 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"grafana-db-exporter/internal/forge"
+	"grafana-db-exporter/internal/logger"
+)
+
+const DefaultAPIURL = "https://api.github.com"
+
+type Client struct {
+	owner string
+	repo  string
+	api   *forge.Client
+}
+
+type PullRequestOptions struct {
+	Head      string
+	Base      string
+	Title     string
+	Body      string
+	Labels    []string
+	Reviewers []string
+	Draft     bool
+}
+
+type PullRequest struct {
+	Number int    `json:"number"`
+	URL    string `json:"html_url"`
+	Head   struct {
+		Ref   string `json:"ref"`
+		Label string `json:"label"`
+	} `json:"head"`
+}
+
+func New(apiURL, token, repoPath string) (*Client, error) {
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	if _, err := url.ParseRequestURI(apiURL); err != nil {
+		return nil, fmt.Errorf("invalid GitHub API URL: %w", err)
+	}
+
+	owner, repo, ok := strings.Cut(repoPath, "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return nil, fmt.Errorf("invalid GitHub repository %q, expected owner/repo", repoPath)
+	}
+
+	return &Client{
+		owner: owner,
+		repo:  repo,
+		api: forge.NewClient(apiURL, map[string]string{
+			"Accept":               "application/vnd.github+json",
+			"Authorization":        "Bearer " + token,
+			"X-GitHub-Api-Version": "2022-11-28",
+		}),
+	}, nil
+}
+
+// EnsurePullRequest updates the open pull request from opts.Head into opts.Base, or creates one if none
+// exists. It reports whether a new pull request was created.
+func (c *Client) EnsurePullRequest(ctx context.Context, opts PullRequestOptions) (*PullRequest, bool, error) {
+	existing, err := c.findPullRequest(ctx, opts.Head, opts.Base)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var pr *PullRequest
+	created := existing == nil
+	if created {
+		logger.Log.Debug().Str("head", opts.Head).Str("base", opts.Base).Msg("Creating GitHub pull request")
+		pr = &PullRequest{}
+		err = c.api.Do(ctx, http.MethodPost, c.repoPath("pulls"), map[string]interface{}{
+			"title": opts.Title,
+			"body":  opts.Body,
+			"head":  opts.Head,
+			"base":  opts.Base,
+			"draft": opts.Draft,
+		}, pr)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to create pull request: %w", err)
+		}
+	} else {
+		logger.Log.Debug().Int("number", existing.Number).Msg("Updating GitHub pull request")
+		pr = existing
+		err = c.api.Do(ctx, http.MethodPatch, c.repoPath(fmt.Sprintf("pulls/%d", pr.Number)), map[string]interface{}{
+			"title": opts.Title,
+			"body":  opts.Body,
+		}, nil)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to update pull request #%d: %w", pr.Number, err)
+		}
+	}
+
+	if len(opts.Labels) > 0 {
+		err = c.api.Do(ctx, http.MethodPost, c.repoPath(fmt.Sprintf("issues/%d/labels", pr.Number)), map[string]interface{}{
+			"labels": opts.Labels,
+		}, nil)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to add labels to pull request #%d: %w", pr.Number, err)
+		}
+	}
+
+	if len(opts.Reviewers) > 0 {
+		users, teams := splitReviewers(opts.Reviewers)
+		err = c.api.Do(ctx, http.MethodPost, c.repoPath(fmt.Sprintf("pulls/%d/requested_reviewers", pr.Number)), map[string]interface{}{
+			"reviewers":      users,
+			"team_reviewers": teams,
+		}, nil)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to request reviewers for pull request #%d: %w", pr.Number, err)
+		}
+	}
+
+	return pr, created, nil
+}
+
+func (c *Client) findPullRequest(ctx context.Context, head, base string) (*PullRequest, error) {
+	query := url.Values{}
+	query.Set("state", "open")
+	query.Set("head", c.owner+":"+head)
+	query.Set("base", base)
+
+	var prs []PullRequest
+	if err := c.api.Do(ctx, http.MethodGet, c.repoPath("pulls")+"?"+query.Encode(), nil, &prs); err != nil {
+		return nil, fmt.Errorf("failed to list pull requests: %w", err)
+	}
+	if len(prs) == 0 {
+		return nil, nil
+	}
+	return &prs[0], nil
+}
+
+// CloseSupersededPullRequests closes the open pull requests into base from branches of this repository
+// that start with prefix, except the one from keep. It returns the numbers of the closed pull requests.
+func (c *Client) CloseSupersededPullRequests(ctx context.Context, prefix, base, keep string) ([]int, error) {
+	query := url.Values{}
+	query.Set("state", "open")
+	query.Set("base", base)
+	query.Set("per_page", "100")
+
+	// Collect all pages first, as closing pull requests would shift the later pages.
+	var superseded []int
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		var prs []PullRequest
+		if err := c.api.Do(ctx, http.MethodGet, c.repoPath("pulls")+"?"+query.Encode(), nil, &prs); err != nil {
+			return nil, fmt.Errorf("failed to list pull requests: %w", err)
+		}
+		for _, pr := range prs {
+			// Pull requests from forks are labeled with the fork owner.
+			if pr.Head.Ref != keep && strings.HasPrefix(pr.Head.Label, c.owner+":"+prefix) {
+				superseded = append(superseded, pr.Number)
+			}
+		}
+		if len(prs) < 100 {
+			break
+		}
+	}
+
+	var closed []int
+	for _, number := range superseded {
+		logger.Log.Debug().Int("number", number).Msg("Closing superseded GitHub pull request")
+		err := c.api.Do(ctx, http.MethodPatch, c.repoPath(fmt.Sprintf("pulls/%d", number)), map[string]interface{}{
+			"state": "closed",
+		}, nil)
+		if err != nil {
+			return closed, fmt.Errorf("failed to close pull request #%d: %w", number, err)
+		}
+		closed = append(closed, number)
+	}
+	return closed, nil
+}
+
+// splitReviewers separates user logins from team reviewers given as org/team-slug.
+func splitReviewers(reviewers []string) ([]string, []string) {
+	users := []string{}
+	teams := []string{}
+	for _, r := range reviewers {
+		if _, team, ok := strings.Cut(r, "/"); ok {
+			teams = append(teams, team)
+		} else {
+			users = append(users, r)
+		}
+	}
+	return users, teams
+}
+
+func (c *Client) repoPath(path string) string {
+	return fmt.Sprintf("/repos/%s/%s/%s", url.PathEscape(c.owner), url.PathEscape(c.repo), path)
+}
@@ -0,0 +1,202 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"grafana-db-exporter/internal/forge/forgetest"
+)
+
+func newStubServer(t *testing.T, existing []PullRequest) *forgetest.Server {
+	t.Helper()
+	return forgetest.NewServer(t, "Authorization", "Bearer test-token", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/org/dashboards/pulls":
+			_ = json.NewEncoder(w).Encode(existing)
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/org/dashboards/pulls":
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(PullRequest{Number: 42, URL: "https://github.com/org/dashboards/pull/42"})
+		case r.Method == http.MethodPatch && r.URL.Path == "/repos/org/dashboards/pulls/7":
+			_ = json.NewEncoder(w).Encode(PullRequest{Number: 7})
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/org/dashboards/issues/42/labels",
+			r.Method == http.MethodPost && r.URL.Path == "/repos/org/dashboards/issues/7/labels",
+			r.Method == http.MethodPost && r.URL.Path == "/repos/org/dashboards/pulls/42/requested_reviewers",
+			r.Method == http.MethodPost && r.URL.Path == "/repos/org/dashboards/pulls/7/requested_reviewers":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+		}
+	})
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		apiURL   string
+		repoPath string
+		wantErr  bool
+	}{
+		{name: "Default API URL", repoPath: "org/repo"},
+		{name: "Enterprise API URL", apiURL: "https://github.example.com/api/v3", repoPath: "org/repo"},
+		{name: "Invalid API URL", apiURL: "not a url", repoPath: "org/repo", wantErr: true},
+		{name: "Missing owner", repoPath: "repo", wantErr: true},
+		{name: "Nested path", repoPath: "group/sub/repo", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.apiURL, "token", tt.repoPath)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_EnsurePullRequest_Create(t *testing.T) {
+	server := newStubServer(t, []PullRequest{})
+	client, err := New(server.URL, "test-token", "org/dashboards")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	pr, created, err := client.EnsurePullRequest(context.Background(), PullRequestOptions{
+		Head:      "grafana-db-exporter/sync",
+		Base:      "main",
+		Title:     "Update Grafana dashboards",
+		Body:      "body",
+		Labels:    []string{"grafana"},
+		Reviewers: []string{"alice", "org/observability"},
+		Draft:     true,
+	})
+	if err != nil {
+		t.Fatalf("EnsurePullRequest() error = %v", err)
+	}
+	if !created || pr.Number != 42 || pr.URL != "https://github.com/org/dashboards/pull/42" {
+		t.Errorf("EnsurePullRequest() = %+v, created %v, want #42 created", pr, created)
+	}
+
+	wantCalls := []string{
+		"GET /repos/org/dashboards/pulls",
+		"POST /repos/org/dashboards/pulls",
+		"POST /repos/org/dashboards/issues/42/labels",
+		"POST /repos/org/dashboards/pulls/42/requested_reviewers",
+	}
+	calls := server.Calls()
+	if len(calls) != len(wantCalls) {
+		t.Fatalf("EnsurePullRequest() calls = %v, want %v", calls, wantCalls)
+	}
+	for i := range calls {
+		if calls[i] != wantCalls[i] {
+			t.Errorf("EnsurePullRequest() call[%d] = %s, want %s", i, calls[i], wantCalls[i])
+		}
+	}
+
+	list := server.Request(http.MethodGet, "/repos/org/dashboards/pulls")
+	if list.Query != "base=main&head=org%3Agrafana-db-exporter%2Fsync&state=open" {
+		t.Errorf("List query = %s", list.Query)
+	}
+
+	create := server.Request(http.MethodPost, "/repos/org/dashboards/pulls")
+	if create.Body["head"] != "grafana-db-exporter/sync" || create.Body["base"] != "main" || create.Body["draft"] != true {
+		t.Errorf("Create body = %v", create.Body)
+	}
+
+	reviewers := server.Request(http.MethodPost, "/repos/org/dashboards/pulls/42/requested_reviewers")
+	users, _ := reviewers.Body["reviewers"].([]interface{})
+	teams, _ := reviewers.Body["team_reviewers"].([]interface{})
+	if len(users) != 1 || users[0] != "alice" || len(teams) != 1 || teams[0] != "observability" {
+		t.Errorf("Reviewers body = %v", reviewers.Body)
+	}
+}
+
+func TestClient_EnsurePullRequest_Update(t *testing.T) {
+	server := newStubServer(t, []PullRequest{{Number: 7, URL: "https://github.com/org/dashboards/pull/7"}})
+	client, err := New(server.URL, "test-token", "org/dashboards")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	pr, created, err := client.EnsurePullRequest(context.Background(), PullRequestOptions{
+		Head:      "grafana-db-exporter/sync",
+		Base:      "main",
+		Title:     "New title",
+		Body:      "New body",
+		Labels:    []string{"grafana"},
+		Reviewers: []string{"alice"},
+	})
+	if err != nil {
+		t.Fatalf("EnsurePullRequest() error = %v", err)
+	}
+	if created || pr.Number != 7 {
+		t.Errorf("EnsurePullRequest() = %+v, created %v, want existing #7", pr, created)
+	}
+
+	if server.Request(http.MethodPost, "/repos/org/dashboards/pulls") != nil {
+		t.Errorf("EnsurePullRequest() created a duplicate pull request")
+	}
+	update := server.Request(http.MethodPatch, "/repos/org/dashboards/pulls/7")
+	if update == nil || update.Body["title"] != "New title" || update.Body["body"] != "New body" {
+		t.Errorf("Update request = %+v", update)
+	}
+	if server.Request(http.MethodPost, "/repos/org/dashboards/issues/7/labels") == nil {
+		t.Errorf("EnsurePullRequest() did not add labels to the existing pull request")
+	}
+	if server.Request(http.MethodPost, "/repos/org/dashboards/pulls/7/requested_reviewers") == nil {
+		t.Errorf("EnsurePullRequest() did not request reviewers on the existing pull request")
+	}
+}
+
+func TestClient_EnsurePullRequest_Error(t *testing.T) {
+	server := newStubServer(t, nil)
+	client, err := New(server.URL, "wrong-token", "org/dashboards")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if _, _, err := client.EnsurePullRequest(context.Background(), PullRequestOptions{Head: "b", Base: "main"}); err == nil {
+		t.Errorf("EnsurePullRequest() should return an error on unauthorized responses")
+	}
+}
+
+func TestClient_CloseSupersededPullRequests(t *testing.T) {
+	server := forgetest.NewServer(t, "Authorization", "Bearer test-token", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/org/dashboards/pulls":
+			_, _ = w.Write([]byte(`[
+				{"number":5,"head":{"ref":"grafana-db-exporter-20240101000000","label":"org:grafana-db-exporter-20240101000000"}},
+				{"number":6,"head":{"ref":"grafana-db-exporter-20240102000000","label":"org:grafana-db-exporter-20240102000000"}},
+				{"number":7,"head":{"ref":"feature","label":"org:feature"}},
+				{"number":8,"head":{"ref":"grafana-db-exporter-20240101000000","label":"fork:grafana-db-exporter-20240101000000"}}
+			]`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/repos/org/dashboards/pulls/5":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+		}
+	})
+	client, err := New(server.URL, "test-token", "org/dashboards")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	closed, err := client.CloseSupersededPullRequests(context.Background(), "grafana-db-exporter-", "main", "grafana-db-exporter-20240102000000")
+	if err != nil {
+		t.Fatalf("CloseSupersededPullRequests() error = %v", err)
+	}
+	if len(closed) != 1 || closed[0] != 5 {
+		t.Errorf("CloseSupersededPullRequests() = %v, want [5]", closed)
+	}
+
+	list := server.Request(http.MethodGet, "/repos/org/dashboards/pulls")
+	if list.Query != "base=main&page=1&per_page=100&state=open" {
+		t.Errorf("List query = %s", list.Query)
+	}
+	if update := server.Request(http.MethodPatch, "/repos/org/dashboards/pulls/5"); update == nil || update.Body["state"] != "closed" {
+		t.Errorf("Close request = %+v", update)
+	}
+}
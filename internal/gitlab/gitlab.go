@@ -0,0 +1,209 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"grafana-db-exporter/internal/forge"
+	"grafana-db-exporter/internal/logger"
+)
+
+const DefaultAPIURL = "https://gitlab.com/api/v4"
+
+const draftPrefix = "Draft: "
+
+type Client struct {
+	project string
+	api     *forge.Client
+}
+
+type MergeRequestOptions struct {
+	SourceBranch       string
+	TargetBranch       string
+	Title              string
+	Description        string
+	Labels             []string
+	Assignees          []string
+	Reviewers          []string
+	Draft              bool
+	RemoveSourceBranch bool
+	AutoMerge          bool
+}
+
+type MergeRequest struct {
+	IID             int    `json:"iid"`
+	WebURL          string `json:"web_url"`
+	SourceBranch    string `json:"source_branch"`
+	SourceProjectID int    `json:"source_project_id"`
+	TargetProjectID int    `json:"target_project_id"`
+}
+
+func New(apiURL, token, projectPath string) (*Client, error) {
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	if _, err := url.ParseRequestURI(apiURL); err != nil {
+		return nil, fmt.Errorf("invalid GitLab API URL: %w", err)
+	}
+	if !strings.Contains(projectPath, "/") {
+		return nil, fmt.Errorf("invalid GitLab project %q, expected group/project", projectPath)
+	}
+
+	return &Client{
+		project: url.PathEscape(projectPath),
+		api:     forge.NewClient(apiURL, map[string]string{"PRIVATE-TOKEN": token}),
+	}, nil
+}
+
+// EnsureMergeRequest updates the open merge request from opts.SourceBranch into opts.TargetBranch, or creates
+// one if none exists. It reports whether a new merge request was created.
+func (c *Client) EnsureMergeRequest(ctx context.Context, opts MergeRequestOptions) (*MergeRequest, bool, error) {
+	existing, err := c.findMergeRequest(ctx, opts.SourceBranch, opts.TargetBranch)
+	if err != nil {
+		return nil, false, err
+	}
+
+	assigneeIDs, err := c.userIDs(ctx, opts.Assignees)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to resolve assignees: %w", err)
+	}
+	reviewerIDs, err := c.userIDs(ctx, opts.Reviewers)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to resolve reviewers: %w", err)
+	}
+
+	title := opts.Title
+	if opts.Draft && !strings.HasPrefix(title, draftPrefix) {
+		title = draftPrefix + title
+	}
+
+	fields := map[string]interface{}{
+		"title":                title,
+		"description":          opts.Description,
+		"remove_source_branch": opts.RemoveSourceBranch,
+	}
+	if len(assigneeIDs) > 0 {
+		fields["assignee_ids"] = assigneeIDs
+	}
+	if len(reviewerIDs) > 0 {
+		fields["reviewer_ids"] = reviewerIDs
+	}
+
+	var mr *MergeRequest
+	created := existing == nil
+	if created {
+		logger.Log.Debug().Str("source", opts.SourceBranch).Str("target", opts.TargetBranch).Msg("Creating GitLab merge request")
+		fields["source_branch"] = opts.SourceBranch
+		fields["target_branch"] = opts.TargetBranch
+		fields["labels"] = strings.Join(opts.Labels, ",")
+
+		mr = &MergeRequest{}
+		if err := c.api.Do(ctx, http.MethodPost, c.projectPath("merge_requests"), fields, mr); err != nil {
+			return nil, false, fmt.Errorf("failed to create merge request: %w", err)
+		}
+	} else {
+		logger.Log.Debug().Int("iid", existing.IID).Msg("Updating GitLab merge request")
+		if len(opts.Labels) > 0 {
+			fields["add_labels"] = strings.Join(opts.Labels, ",")
+		}
+
+		mr = existing
+		if err := c.api.Do(ctx, http.MethodPut, c.projectPath(fmt.Sprintf("merge_requests/%d", mr.IID)), fields, nil); err != nil {
+			return nil, false, fmt.Errorf("failed to update merge request !%d: %w", mr.IID, err)
+		}
+	}
+
+	if opts.AutoMerge && !opts.Draft {
+		err := c.api.Do(ctx, http.MethodPut, c.projectPath(fmt.Sprintf("merge_requests/%d/merge", mr.IID)), map[string]interface{}{
+			"merge_when_pipeline_succeeds": true,
+			"should_remove_source_branch":  opts.RemoveSourceBranch,
+		}, nil)
+		if err != nil {
+			// The merge request may not be mergeable yet, e.g. while its pipeline is being created.
+			logger.Log.Warn().Err(err).Int("iid", mr.IID).Msg("Failed to enable auto-merge for merge request")
+		}
+	}
+
+	return mr, created, nil
+}
+
+func (c *Client) findMergeRequest(ctx context.Context, source, target string) (*MergeRequest, error) {
+	query := url.Values{}
+	query.Set("state", "opened")
+	query.Set("source_branch", source)
+	query.Set("target_branch", target)
+
+	var mrs []MergeRequest
+	if err := c.api.Do(ctx, http.MethodGet, c.projectPath("merge_requests")+"?"+query.Encode(), nil, &mrs); err != nil {
+		return nil, fmt.Errorf("failed to list merge requests: %w", err)
+	}
+	if len(mrs) == 0 {
+		return nil, nil
+	}
+	return &mrs[0], nil
+}
+
+// CloseSupersededMergeRequests closes the open merge requests into target from branches of this project
+// that start with prefix, except the one from keep. It returns the IIDs of the closed merge requests.
+func (c *Client) CloseSupersededMergeRequests(ctx context.Context, prefix, target, keep string) ([]int, error) {
+	query := url.Values{}
+	query.Set("state", "opened")
+	query.Set("target_branch", target)
+	query.Set("per_page", "100")
+
+	// Collect all pages first, as closing merge requests would shift the later pages.
+	var superseded []int
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		var mrs []MergeRequest
+		if err := c.api.Do(ctx, http.MethodGet, c.projectPath("merge_requests")+"?"+query.Encode(), nil, &mrs); err != nil {
+			return nil, fmt.Errorf("failed to list merge requests: %w", err)
+		}
+		for _, mr := range mrs {
+			if mr.SourceProjectID == mr.TargetProjectID && mr.SourceBranch != keep && strings.HasPrefix(mr.SourceBranch, prefix) {
+				superseded = append(superseded, mr.IID)
+			}
+		}
+		if len(mrs) < 100 {
+			break
+		}
+	}
+
+	var closed []int
+	for _, iid := range superseded {
+		logger.Log.Debug().Int("iid", iid).Msg("Closing superseded GitLab merge request")
+		err := c.api.Do(ctx, http.MethodPut, c.projectPath(fmt.Sprintf("merge_requests/%d", iid)), map[string]interface{}{
+			"state_event": "close",
+		}, nil)
+		if err != nil {
+			return closed, fmt.Errorf("failed to close merge request !%d: %w", iid, err)
+		}
+		closed = append(closed, iid)
+	}
+	return closed, nil
+}
+
+func (c *Client) userIDs(ctx context.Context, usernames []string) ([]int, error) {
+	var ids []int
+	for _, username := range usernames {
+		var users []struct {
+			ID int `json:"id"`
+		}
+		if err := c.api.Do(ctx, http.MethodGet, "/users?username="+url.QueryEscape(username), nil, &users); err != nil {
+			return nil, err
+		}
+		if len(users) == 0 {
+			return nil, fmt.Errorf("user %q not found", username)
+		}
+		ids = append(ids, users[0].ID)
+	}
+	return ids, nil
+}
+
+func (c *Client) projectPath(path string) string {
+	return fmt.Sprintf("/projects/%s/%s", c.project, path)
+}
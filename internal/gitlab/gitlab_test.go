@@ -0,0 +1,211 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"grafana-db-exporter/internal/forge/forgetest"
+)
+
+const projectPath = "/api/v4/projects/group%2Fsub%2Fdashboards"
+
+func newStubServer(t *testing.T, existing []MergeRequest, mergeStatus int) *forgetest.Server {
+	t.Helper()
+	return forgetest.NewServer(t, "PRIVATE-TOKEN", "test-token", func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.EscapedPath()
+		switch {
+		case r.Method == http.MethodGet && path == "/api/v4/users":
+			switch r.URL.Query().Get("username") {
+			case "alice":
+				_, _ = w.Write([]byte(`[{"id":11}]`))
+			case "bob":
+				_, _ = w.Write([]byte(`[{"id":12}]`))
+			default:
+				_, _ = w.Write([]byte(`[]`))
+			}
+		case r.Method == http.MethodGet && path == projectPath+"/merge_requests":
+			_ = json.NewEncoder(w).Encode(existing)
+		case r.Method == http.MethodPost && path == projectPath+"/merge_requests":
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(MergeRequest{IID: 5, WebURL: "https://gitlab.example.com/group/sub/dashboards/-/merge_requests/5"})
+		case r.Method == http.MethodPut && path == projectPath+"/merge_requests/3":
+			_ = json.NewEncoder(w).Encode(MergeRequest{IID: 3})
+		case r.Method == http.MethodPut && (path == projectPath+"/merge_requests/5/merge" || path == projectPath+"/merge_requests/3/merge"):
+			w.WriteHeader(mergeStatus)
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"404 Not Found"}`))
+		}
+	})
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		apiURL      string
+		projectPath string
+		wantErr     bool
+	}{
+		{name: "Default API URL", projectPath: "group/project"},
+		{name: "Nested group", apiURL: "https://gitlab.example.com/api/v4", projectPath: "group/sub/project"},
+		{name: "Invalid API URL", apiURL: "gitlab", projectPath: "group/project", wantErr: true},
+		{name: "Missing group", projectPath: "project", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.apiURL, "token", tt.projectPath)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_EnsureMergeRequest_Create(t *testing.T) {
+	server := newStubServer(t, []MergeRequest{}, http.StatusOK)
+	client, err := New(server.URL+"/api/v4", "test-token", "group/sub/dashboards")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	mr, created, err := client.EnsureMergeRequest(context.Background(), MergeRequestOptions{
+		SourceBranch:       "grafana-db-exporter/sync",
+		TargetBranch:       "main",
+		Title:              "Update Grafana dashboards",
+		Description:        "description",
+		Labels:             []string{"grafana", "automated"},
+		Assignees:          []string{"alice"},
+		Reviewers:          []string{"bob"},
+		RemoveSourceBranch: true,
+		AutoMerge:          true,
+	})
+	if err != nil {
+		t.Fatalf("EnsureMergeRequest() error = %v", err)
+	}
+	if !created || mr.IID != 5 {
+		t.Errorf("EnsureMergeRequest() = %+v, created %v, want !5 created", mr, created)
+	}
+
+	list := server.Request(http.MethodGet, projectPath+"/merge_requests")
+	if list == nil || list.Query != "source_branch=grafana-db-exporter%2Fsync&state=opened&target_branch=main" {
+		t.Errorf("List request = %+v", list)
+	}
+
+	create := server.Request(http.MethodPost, projectPath+"/merge_requests")
+	if create == nil {
+		t.Fatalf("EnsureMergeRequest() did not create a merge request")
+	}
+	assignees, _ := create.Body["assignee_ids"].([]interface{})
+	reviewers, _ := create.Body["reviewer_ids"].([]interface{})
+	if create.Body["source_branch"] != "grafana-db-exporter/sync" ||
+		create.Body["labels"] != "grafana,automated" ||
+		create.Body["remove_source_branch"] != true ||
+		len(assignees) != 1 || assignees[0] != float64(11) ||
+		len(reviewers) != 1 || reviewers[0] != float64(12) {
+		t.Errorf("Create body = %v", create.Body)
+	}
+
+	merge := server.Request(http.MethodPut, projectPath+"/merge_requests/5/merge")
+	if merge == nil || merge.Body["merge_when_pipeline_succeeds"] != true {
+		t.Errorf("Auto-merge request = %+v", merge)
+	}
+}
+
+func TestClient_EnsureMergeRequest_Update(t *testing.T) {
+	server := newStubServer(t, []MergeRequest{{IID: 3}}, http.StatusMethodNotAllowed)
+	client, err := New(server.URL+"/api/v4", "test-token", "group/sub/dashboards")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	mr, created, err := client.EnsureMergeRequest(context.Background(), MergeRequestOptions{
+		SourceBranch: "grafana-db-exporter/sync",
+		TargetBranch: "main",
+		Title:        "Update Grafana dashboards",
+		Labels:       []string{"grafana"},
+		Draft:        true,
+		AutoMerge:    true,
+	})
+	if err != nil {
+		t.Fatalf("EnsureMergeRequest() error = %v", err)
+	}
+	if created || mr.IID != 3 {
+		t.Errorf("EnsureMergeRequest() = %+v, created %v, want existing !3", mr, created)
+	}
+
+	if server.Request(http.MethodPost, projectPath+"/merge_requests") != nil {
+		t.Errorf("EnsureMergeRequest() created a duplicate merge request")
+	}
+	update := server.Request(http.MethodPut, projectPath+"/merge_requests/3")
+	if update == nil || update.Body["title"] != "Draft: Update Grafana dashboards" || update.Body["add_labels"] != "grafana" {
+		t.Errorf("Update request = %+v", update)
+	}
+	if server.Request(http.MethodPut, projectPath+"/merge_requests/3/merge") != nil {
+		t.Errorf("EnsureMergeRequest() enabled auto-merge for a draft")
+	}
+}
+
+func TestClient_EnsureMergeRequest_AutoMergeNotAllowed(t *testing.T) {
+	server := newStubServer(t, []MergeRequest{}, http.StatusMethodNotAllowed)
+	client, err := New(server.URL+"/api/v4", "test-token", "group/sub/dashboards")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if _, _, err := client.EnsureMergeRequest(context.Background(), MergeRequestOptions{
+		SourceBranch: "b",
+		TargetBranch: "main",
+		AutoMerge:    true,
+	}); err != nil {
+		t.Errorf("EnsureMergeRequest() error = %v, auto-merge failures should not fail the merge request", err)
+	}
+}
+
+func TestClient_EnsureMergeRequest_UnknownUser(t *testing.T) {
+	server := newStubServer(t, []MergeRequest{}, http.StatusOK)
+	client, err := New(server.URL+"/api/v4", "test-token", "group/sub/dashboards")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if _, _, err := client.EnsureMergeRequest(context.Background(), MergeRequestOptions{
+		SourceBranch: "b",
+		TargetBranch: "main",
+		Assignees:    []string{"mallory"},
+	}); err == nil {
+		t.Errorf("EnsureMergeRequest() should return an error for unknown assignees")
+	}
+}
+
+func TestClient_CloseSupersededMergeRequests(t *testing.T) {
+	server := newStubServer(t, []MergeRequest{
+		{IID: 3, SourceBranch: "grafana-db-exporter-20240101000000", SourceProjectID: 1, TargetProjectID: 1},
+		{IID: 4, SourceBranch: "grafana-db-exporter-20240102000000", SourceProjectID: 1, TargetProjectID: 1},
+		{IID: 6, SourceBranch: "grafana-db-exporter-20240101000000", SourceProjectID: 2, TargetProjectID: 1},
+		{IID: 7, SourceBranch: "feature", SourceProjectID: 1, TargetProjectID: 1},
+	}, http.StatusOK)
+	client, err := New(server.URL+"/api/v4", "test-token", "group/sub/dashboards")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	closed, err := client.CloseSupersededMergeRequests(context.Background(), "grafana-db-exporter-", "main", "grafana-db-exporter-20240102000000")
+	if err != nil {
+		t.Fatalf("CloseSupersededMergeRequests() error = %v", err)
+	}
+	if len(closed) != 1 || closed[0] != 3 {
+		t.Errorf("CloseSupersededMergeRequests() = %v, want [3]", closed)
+	}
+
+	list := server.Request(http.MethodGet, projectPath+"/merge_requests")
+	if list.Query != "page=1&per_page=100&state=opened&target_branch=main" {
+		t.Errorf("List query = %s", list.Query)
+	}
+	if update := server.Request(http.MethodPut, projectPath+"/merge_requests/3"); update == nil || update.Body["state_event"] != "close" {
+		t.Errorf("Close request = %+v", update)
+	}
+}
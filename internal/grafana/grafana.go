@@ -2,6 +2,7 @@ package grafana
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"regexp"
@@ -96,6 +97,15 @@ func (gc *Client) ListAndExportDashboards(ctx context.Context) ([]Dashboard, err
 	return dashboards, nil
 }
 
+// Version returns the version reported by the Grafana health endpoint.
+func (gc *Client) Version(ctx context.Context) (string, error) {
+	health, err := gc.client.GetHealth(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get Grafana health: %w", err)
+	}
+	return health.Version, nil
+}
+
 func SanitizeFolderPath(path string) string {
 	invalidChars := regexp.MustCompile(`[<>:"/\\|?*\x00-\x1F]`)
 	sanitized := invalidChars.ReplaceAllString(path, "-")
@@ -110,3 +120,61 @@ func GetDashboardPath(basePath string, dashboard Dashboard, ignoreFolderStructur
 	folderPath := SanitizeFolderPath(dashboard.FolderTitle)
 	return filepath.Join(basePath, folderPath, fmt.Sprintf("%s.json", dashboard.UID))
 }
+
+func (d *Dashboard) JSONMap() (map[string]interface{}, error) {
+	if m, ok := d.Data.(map[string]interface{}); ok {
+		return m, nil
+	}
+
+	raw, err := json.Marshal(d.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal dashboard data: %w", err)
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dashboard data: %w", err)
+	}
+
+	d.Data = m
+	return m, nil
+}
+
+// ForEachPanel visits top-level panels, panels nested in collapsed rows and legacy row panels.
+func ForEachPanel(data map[string]interface{}, fn func(path string, panel map[string]interface{})) {
+	visit := func(list interface{}, path string) {
+		panels, _ := list.([]interface{})
+		for i, p := range panels {
+			panel, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			panelPath := fmt.Sprintf("%s[%d]", path, i)
+			fn(panelPath, panel)
+
+			nested, _ := panel["panels"].([]interface{})
+			for j, n := range nested {
+				if nestedPanel, ok := n.(map[string]interface{}); ok {
+					fn(fmt.Sprintf("%s.panels[%d]", panelPath, j), nestedPanel)
+				}
+			}
+		}
+	}
+
+	visit(data["panels"], "$.panels")
+
+	rows, _ := data["rows"].([]interface{})
+	for i, r := range rows {
+		if row, ok := r.(map[string]interface{}); ok {
+			visit(row["panels"], fmt.Sprintf("$.rows[%d].panels", i))
+		}
+	}
+}
+
+func PanelID(panel map[string]interface{}) (int, bool) {
+	id, ok := panel["id"].(float64)
+	if !ok {
+		return 0, false
+	}
+	return int(id), true
+}
@@ -2,6 +2,7 @@ package grafana
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
@@ -141,6 +142,44 @@ func TestClient_ListAndExportDashboards(t *testing.T) {
 	}
 }
 
+func TestClient_Version(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		expected string
+		wantErr  bool
+	}{
+		{name: "Healthy", status: http.StatusOK, response: `{"commit":"abc","database":"ok","version":"11.2.0"}`, expected: "11.2.0"},
+		{name: "Invalid response", status: http.StatusOK, response: `invalid json`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/health" {
+					t.Errorf("unexpected request path: %s", r.URL.Path)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			client, err := New(server.URL, "test-token")
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			got, err := client.Version(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Version() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("Version() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestSanitizeFolderPath(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -247,3 +286,54 @@ func TestGetDashboardPath(t *testing.T) {
 		})
 	}
 }
+
+func TestDashboard_JSONMap(t *testing.T) {
+	dashboard := Dashboard{
+		UID: "dash1",
+		Data: struct {
+			UID   string `json:"uid"`
+			Title string `json:"title"`
+		}{UID: "dash1", Title: "Test Dashboard"},
+	}
+
+	data, err := dashboard.JSONMap()
+	if err != nil {
+		t.Fatalf("JSONMap() error = %v", err)
+	}
+
+	if data["title"] != "Test Dashboard" {
+		t.Errorf("JSONMap() title = %v, want 'Test Dashboard'", data["title"])
+	}
+
+	data["title"] = "Changed"
+	again, err := dashboard.JSONMap()
+	if err != nil {
+		t.Fatalf("JSONMap() error = %v", err)
+	}
+	if again["title"] != "Changed" {
+		t.Errorf("JSONMap() did not return the converted map on second call")
+	}
+}
+
+func TestForEachPanel(t *testing.T) {
+	var data map[string]interface{}
+	raw := `{"panels":[{"id":1,"type":"row","panels":[{"id":2}]},{"id":3}],"rows":[{"panels":[{"id":4}]}]}`
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		t.Fatalf("Failed to parse test JSON: %v", err)
+	}
+
+	var paths []string
+	ForEachPanel(data, func(path string, panel map[string]interface{}) {
+		paths = append(paths, path)
+	})
+
+	expected := []string{"$.panels[0]", "$.panels[0].panels[0]", "$.panels[1]", "$.rows[0].panels[0]"}
+	if len(paths) != len(expected) {
+		t.Fatalf("ForEachPanel() visited %v, want %v", paths, expected)
+	}
+	for i := range paths {
+		if paths[i] != expected[i] {
+			t.Errorf("ForEachPanel() path[%d] = %s, want %s", i, paths[i], expected[i])
+		}
+	}
+}
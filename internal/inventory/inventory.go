@@ -0,0 +1,121 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"grafana-db-exporter/internal/grafana"
+	"grafana-db-exporter/internal/logger"
+	"grafana-db-exporter/internal/query"
+)
+
+type Reference struct {
+	DashboardUID   string `json:"dashboardUID"`
+	DashboardTitle string `json:"dashboardTitle"`
+	PanelID        *int   `json:"panelID,omitempty"`
+	PanelTitle     string `json:"panelTitle,omitempty"`
+}
+
+type Inventory struct {
+	Metrics    map[string][]Reference `json:"metrics"`
+	LokiLabels map[string][]Reference `json:"lokiLabels"`
+}
+
+func Build(dashboards []grafana.Dashboard) (*Inventory, error) {
+	inv := &Inventory{
+		Metrics:    make(map[string][]Reference),
+		LokiLabels: make(map[string][]Reference),
+	}
+
+	for i := range dashboards {
+		data, err := dashboards[i].JSONMap()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dashboard %s: %w", dashboards[i].UID, err)
+		}
+
+		for _, q := range query.Extract(data) {
+			ref := Reference{
+				DashboardUID:   dashboards[i].UID,
+				DashboardTitle: dashboards[i].Title,
+				PanelID:        q.PanelID,
+				PanelTitle:     q.PanelTitle,
+			}
+
+			var names []string
+			var index map[string][]Reference
+			switch q.Language {
+			case query.LanguagePromQL:
+				names, err = query.Metrics(q.Expr)
+				index = inv.Metrics
+			case query.LanguageLogQL:
+				names, err = query.StreamLabels(q.Expr)
+				index = inv.LokiLabels
+			default:
+				continue
+			}
+			if err != nil {
+				logger.Log.Debug().
+					Err(err).
+					Str("dashboardUID", dashboards[i].UID).
+					Str("path", q.Path).
+					Msg("Skipping unparseable query in inventory")
+				continue
+			}
+
+			for _, name := range names {
+				index[name] = append(index[name], ref)
+			}
+		}
+	}
+
+	for _, index := range []map[string][]Reference{inv.Metrics, inv.LokiLabels} {
+		for name, refs := range index {
+			index[name] = sortAndDedup(refs)
+		}
+	}
+	return inv, nil
+}
+
+func (inv *Inventory) Write(path string) error {
+	data, err := json.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal inventory: %w", err)
+	}
+	data = append(data, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create inventory directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write inventory: %w", err)
+	}
+	return nil
+}
+
+func sortAndDedup(refs []Reference) []Reference {
+	sort.SliceStable(refs, func(i, j int) bool {
+		if refs[i].DashboardUID != refs[j].DashboardUID {
+			return refs[i].DashboardUID < refs[j].DashboardUID
+		}
+		return panelKey(refs[i]) < panelKey(refs[j])
+	})
+
+	var deduped []Reference
+	for _, ref := range refs {
+		if n := len(deduped); n > 0 && deduped[n-1].DashboardUID == ref.DashboardUID && panelKey(deduped[n-1]) == panelKey(ref) {
+			continue
+		}
+		deduped = append(deduped, ref)
+	}
+	return deduped
+}
+
+func panelKey(ref Reference) int {
+	if ref.PanelID == nil {
+		return -1
+	}
+	return *ref.PanelID
+}
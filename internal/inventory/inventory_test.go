@@ -0,0 +1,82 @@
+package inventory
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"grafana-db-exporter/internal/grafana"
+)
+
+func dashboard(t *testing.T, uid, title, raw string) grafana.Dashboard {
+	t.Helper()
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		t.Fatalf("Failed to parse test JSON: %v", err)
+	}
+	return grafana.Dashboard{UID: uid, Title: title, Data: m}
+}
+
+func TestBuild(t *testing.T) {
+	dashboards := []grafana.Dashboard{
+		dashboard(t, "b-dash", "B", `{"panels":[
+			{"id":2,"title":"Errors","datasource":{"type":"prometheus"},"targets":[
+				{"expr":"sum(rate(http_requests_total{code=~\"5..\"}[5m])) / sum(rate(http_requests_total[5m]))"}]},
+			{"id":1,"title":"Logs","datasource":{"type":"loki"},"targets":[{"expr":"{app=\"api\", env=\"$env\"} |= \"error\""}]}]}`),
+		dashboard(t, "a-dash", "A", `{"panels":[
+			{"id":1,"title":"Up","datasource":{"type":"prometheus"},"targets":[{"expr":"up{job=\"$job\"}"},{"expr":"rate($metric[5m])"},{"expr":"rate(broken[5m]"}]},
+			{"id":3,"title":"Requests","datasource":{"type":"prometheus"},"targets":[{"expr":"http_requests_total"}]}]}`),
+	}
+
+	inv, err := Build(dashboards)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if len(inv.Metrics) != 2 {
+		t.Fatalf("Build() metrics = %v, want http_requests_total and up", inv.Metrics)
+	}
+
+	refs := inv.Metrics["http_requests_total"]
+	if len(refs) != 2 || refs[0].DashboardUID != "a-dash" || refs[1].DashboardUID != "b-dash" {
+		t.Errorf("Build() http_requests_total references = %+v, want a-dash then b-dash once each", refs)
+	}
+	if refs[1].PanelID == nil || *refs[1].PanelID != 2 || refs[1].PanelTitle != "Errors" {
+		t.Errorf("Build() reference panel = %+v, want panel 2 Errors", refs[1])
+	}
+
+	if len(inv.Metrics["up"]) != 1 {
+		t.Errorf("Build() up references = %+v, want one", inv.Metrics["up"])
+	}
+
+	if len(inv.LokiLabels) != 2 || len(inv.LokiLabels["app"]) != 1 || len(inv.LokiLabels["env"]) != 1 {
+		t.Errorf("Build() loki labels = %+v, want app and env", inv.LokiLabels)
+	}
+}
+
+func TestInventory_Write(t *testing.T) {
+	id := 1
+	inv := &Inventory{
+		Metrics:    map[string][]Reference{"up": {{DashboardUID: "a", DashboardTitle: "A", PanelID: &id}}},
+		LokiLabels: map[string][]Reference{},
+	}
+
+	path := filepath.Join(t.TempDir(), "inventory", "queries.json")
+	if err := inv.Write(path); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read inventory: %v", err)
+	}
+
+	var decoded Inventory
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("Failed to parse inventory: %v", err)
+	}
+	if len(decoded.Metrics["up"]) != 1 || decoded.Metrics["up"][0].DashboardUID != "a" {
+		t.Errorf("Write() produced unexpected inventory: %s", raw)
+	}
+}
@@ -0,0 +1,301 @@
+package lint
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"grafana-db-exporter/internal/grafana"
+)
+
+type Severity string
+
+const (
+	SeverityOff     Severity = "off"
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+const (
+	RuleHardcodedDatasource = "hardcoded-datasource"
+	RuleMissingDescription  = "missing-description"
+	RuleMinRefreshInterval  = "min-refresh-interval"
+	RulePanelMissingTitle   = "panel-missing-title"
+	RuleDuplicatePanelID    = "duplicate-panel-id"
+
+	MinRefreshInterval = 30 * time.Second
+)
+
+type Violation struct {
+	DashboardUID string   `json:"dashboardUID"`
+	Rule         string   `json:"rule"`
+	Severity     Severity `json:"severity"`
+	Path         string   `json:"path"`
+	PanelID      *int     `json:"panelID,omitempty"`
+	PanelTitle   string   `json:"panelTitle,omitempty"`
+	Message      string   `json:"message"`
+}
+
+type finding struct {
+	path    string
+	panel   map[string]interface{}
+	message string
+}
+
+type rule struct {
+	name     string
+	severity Severity
+	check    func(data map[string]interface{}) []finding
+}
+
+var rules = []rule{
+	{RuleHardcodedDatasource, SeverityWarning, checkHardcodedDatasource},
+	{RuleMissingDescription, SeverityInfo, checkMissingDescription},
+	{RuleMinRefreshInterval, SeverityWarning, checkMinRefreshInterval},
+	{RulePanelMissingTitle, SeverityWarning, checkPanelMissingTitle},
+	{RuleDuplicatePanelID, SeverityError, checkDuplicatePanelID},
+}
+
+// Built-in data sources that cannot be replaced by a variable.
+var builtinDatasources = map[string]bool{
+	"grafana":         true,
+	"-- Grafana --":   true,
+	"-- Mixed --":     true,
+	"-- Dashboard --": true,
+	"dashboard":       true,
+}
+
+type Linter struct {
+	severities map[string]Severity
+}
+
+// ParseSeverities parses overrides in the form "rule=severity,rule=severity".
+func ParseSeverities(spec string) (map[string]Severity, error) {
+	overrides := make(map[string]Severity)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid lint severity %q, expected rule=severity", item)
+		}
+		name = strings.TrimSpace(name)
+		severity := Severity(strings.ToLower(strings.TrimSpace(value)))
+
+		if !knownRule(name) {
+			return nil, fmt.Errorf("unknown lint rule %q", name)
+		}
+		switch severity {
+		case SeverityOff, SeverityInfo, SeverityWarning, SeverityError:
+		default:
+			return nil, fmt.Errorf("invalid severity %q for lint rule %s", value, name)
+		}
+		overrides[name] = severity
+	}
+	return overrides, nil
+}
+
+func New(overrides map[string]Severity) *Linter {
+	severities := make(map[string]Severity)
+	for _, r := range rules {
+		severities[r.name] = r.severity
+	}
+	for name, severity := range overrides {
+		severities[name] = severity
+	}
+	return &Linter{severities: severities}
+}
+
+func (l *Linter) Lint(uid string, data map[string]interface{}) []Violation {
+	var violations []Violation
+	for _, r := range rules {
+		severity := l.severities[r.name]
+		if severity == SeverityOff {
+			continue
+		}
+
+		for _, f := range r.check(data) {
+			v := Violation{
+				DashboardUID: uid,
+				Rule:         r.name,
+				Severity:     severity,
+				Path:         f.path,
+				Message:      f.message,
+			}
+			if f.panel != nil {
+				if id, ok := grafana.PanelID(f.panel); ok {
+					v.PanelID = &id
+				}
+				v.PanelTitle, _ = f.panel["title"].(string)
+			}
+			violations = append(violations, v)
+		}
+	}
+	return violations
+}
+
+func HasErrors(violations []Violation) bool {
+	for _, v := range violations {
+		if v.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func knownRule(name string) bool {
+	for _, r := range rules {
+		if r.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isRow(panel map[string]interface{}) bool {
+	return panel["type"] == "row"
+}
+
+func hardcodedDatasource(ds interface{}) (string, bool) {
+	switch v := ds.(type) {
+	case string:
+		if v == "" || strings.HasPrefix(v, "$") || builtinDatasources[v] {
+			return "", false
+		}
+		return v, true
+	case map[string]interface{}:
+		uid, _ := v["uid"].(string)
+		if uid == "" || strings.HasPrefix(uid, "$") || builtinDatasources[uid] || v["type"] == "datasource" {
+			return "", false
+		}
+		return uid, true
+	default:
+		return "", false
+	}
+}
+
+func checkHardcodedDatasource(data map[string]interface{}) []finding {
+	var findings []finding
+	grafana.ForEachPanel(data, func(path string, panel map[string]interface{}) {
+		if uid, ok := hardcodedDatasource(panel["datasource"]); ok {
+			findings = append(findings, finding{
+				path:    path + ".datasource",
+				panel:   panel,
+				message: fmt.Sprintf("panel uses hardcoded data source %q instead of a variable", uid),
+			})
+		}
+
+		targets, _ := panel["targets"].([]interface{})
+		for i, t := range targets {
+			target, ok := t.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if uid, ok := hardcodedDatasource(target["datasource"]); ok {
+				findings = append(findings, finding{
+					path:    fmt.Sprintf("%s.targets[%d].datasource", path, i),
+					panel:   panel,
+					message: fmt.Sprintf("query uses hardcoded data source %q instead of a variable", uid),
+				})
+			}
+		}
+	})
+	return findings
+}
+
+func checkMissingDescription(data map[string]interface{}) []finding {
+	if description, _ := data["description"].(string); strings.TrimSpace(description) != "" {
+		return nil
+	}
+	return []finding{{path: "$.description", message: "dashboard has no description"}}
+}
+
+func checkMinRefreshInterval(data map[string]interface{}) []finding {
+	refresh, _ := data["refresh"].(string)
+	if refresh == "" {
+		return nil
+	}
+
+	interval, err := ParseInterval(refresh)
+	if err != nil {
+		return []finding{{path: "$.refresh", message: fmt.Sprintf("refresh interval %q cannot be parsed", refresh)}}
+	}
+	if interval < MinRefreshInterval {
+		return []finding{{
+			path:    "$.refresh",
+			message: fmt.Sprintf("refresh interval %s is below the minimum of %s", refresh, MinRefreshInterval),
+		}}
+	}
+	return nil
+}
+
+func checkPanelMissingTitle(data map[string]interface{}) []finding {
+	var findings []finding
+	grafana.ForEachPanel(data, func(path string, panel map[string]interface{}) {
+		if isRow(panel) {
+			return
+		}
+		if title, _ := panel["title"].(string); strings.TrimSpace(title) == "" {
+			findings = append(findings, finding{path: path + ".title", panel: panel, message: "panel has no title"})
+		}
+	})
+	return findings
+}
+
+func checkDuplicatePanelID(data map[string]interface{}) []finding {
+	seen := make(map[int]string)
+	var findings []finding
+	grafana.ForEachPanel(data, func(path string, panel map[string]interface{}) {
+		id, ok := grafana.PanelID(panel)
+		if !ok {
+			return
+		}
+		if first, dup := seen[id]; dup {
+			findings = append(findings, finding{
+				path:    path + ".id",
+				panel:   panel,
+				message: fmt.Sprintf("panel ID %d is also used by %s", id, first),
+			})
+			return
+		}
+		seen[id] = path
+	})
+	return findings
+}
+
+// ParseInterval parses Grafana interval strings such as "30s", "5m", "1h" or "1d".
+func ParseInterval(s string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"ms": time.Millisecond,
+		"s":  time.Second,
+		"m":  time.Minute,
+		"h":  time.Hour,
+		"d":  24 * time.Hour,
+		"w":  7 * 24 * time.Hour,
+		"y":  365 * 24 * time.Hour,
+	}
+
+	s = strings.TrimSpace(s)
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	if i == 0 {
+		return 0, fmt.Errorf("invalid interval %q", s)
+	}
+
+	value, err := strconv.Atoi(s[:i])
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval %q: %w", s, err)
+	}
+	unit, ok := units[s[i:]]
+	if !ok {
+		return 0, fmt.Errorf("invalid interval unit in %q", s)
+	}
+	return time.Duration(value) * unit, nil
+}
@@ -0,0 +1,186 @@
+package lint
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func parse(t *testing.T, raw string) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		t.Fatalf("Failed to parse test JSON: %v", err)
+	}
+	return m
+}
+
+func TestLinter_Lint(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		overrides map[string]Severity
+		wantRules map[string]int
+		wantPaths []string
+	}{
+		{
+			name:      "Clean dashboard",
+			input:     `{"description":"d","refresh":"1m","panels":[{"id":1,"title":"CPU","datasource":{"type":"prometheus","uid":"${ds}"}}]}`,
+			wantRules: map[string]int{},
+		},
+		{
+			name: "Hardcoded data sources",
+			input: `{"description":"d","panels":[
+				{"id":1,"title":"A","datasource":{"type":"prometheus","uid":"P1809F7CD0C75ACF3"}},
+				{"id":2,"title":"B","datasource":"Prometheus","targets":[{"datasource":{"uid":"abc"}},{"datasource":{"uid":"$ds"}}]},
+				{"id":3,"title":"C","datasource":{"type":"datasource","uid":"grafana"}}]}`,
+			wantRules: map[string]int{RuleHardcodedDatasource: 3},
+			wantPaths: []string{"$.panels[0].datasource", "$.panels[1].datasource", "$.panels[1].targets[0].datasource"},
+		},
+		{
+			name:      "Missing description",
+			input:     `{"panels":[]}`,
+			wantRules: map[string]int{RuleMissingDescription: 1},
+		},
+		{
+			name:      "Refresh below minimum",
+			input:     `{"description":"d","refresh":"10s"}`,
+			wantRules: map[string]int{RuleMinRefreshInterval: 1},
+		},
+		{
+			name:      "Panels without titles in collapsed rows",
+			input:     `{"description":"d","panels":[{"id":1,"type":"row","panels":[{"id":2,"title":""}]},{"id":3}]}`,
+			wantRules: map[string]int{RulePanelMissingTitle: 2},
+			wantPaths: []string{"$.panels[0].panels[0].title", "$.panels[1].title"},
+		},
+		{
+			name:      "Duplicate panel IDs",
+			input:     `{"description":"d","panels":[{"id":1,"title":"a"},{"id":1,"title":"b"}]}`,
+			wantRules: map[string]int{RuleDuplicatePanelID: 1},
+			wantPaths: []string{"$.panels[1].id"},
+		},
+		{
+			name:      "Rule turned off",
+			input:     `{"panels":[]}`,
+			overrides: map[string]Severity{RuleMissingDescription: SeverityOff},
+			wantRules: map[string]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := New(tt.overrides).Lint("dash1", parse(t, tt.input))
+
+			got := make(map[string]int)
+			var paths []string
+			for _, v := range violations {
+				got[v.Rule]++
+				paths = append(paths, v.Path)
+				if v.DashboardUID != "dash1" {
+					t.Errorf("Violation has dashboard UID %q, want dash1", v.DashboardUID)
+				}
+			}
+
+			if len(got) != len(tt.wantRules) {
+				t.Errorf("Lint() rules = %v, want %v", got, tt.wantRules)
+			}
+			for rule, count := range tt.wantRules {
+				if got[rule] != count {
+					t.Errorf("Lint() %s violations = %d, want %d", rule, got[rule], count)
+				}
+			}
+
+			if tt.wantPaths != nil {
+				if len(paths) != len(tt.wantPaths) {
+					t.Fatalf("Lint() paths = %v, want %v", paths, tt.wantPaths)
+				}
+				for i := range paths {
+					if paths[i] != tt.wantPaths[i] {
+						t.Errorf("Lint() path[%d] = %s, want %s", i, paths[i], tt.wantPaths[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestLinter_SeverityOverride(t *testing.T) {
+	linter := New(map[string]Severity{RuleMissingDescription: SeverityError})
+	violations := linter.Lint("dash1", parse(t, `{}`))
+
+	if len(violations) != 1 || violations[0].Severity != SeverityError {
+		t.Fatalf("Lint() = %+v, want one error-level violation", violations)
+	}
+	if !HasErrors(violations) {
+		t.Errorf("HasErrors() = false, want true")
+	}
+	if HasErrors(New(nil).Lint("dash1", parse(t, `{}`))) {
+		t.Errorf("HasErrors() = true for info-level violations")
+	}
+}
+
+func TestParseSeverities(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    map[string]Severity
+		wantErr bool
+	}{
+		{name: "Empty", spec: "", want: map[string]Severity{}},
+		{
+			name: "Multiple overrides",
+			spec: "missing-description=off, hardcoded-datasource=ERROR",
+			want: map[string]Severity{RuleMissingDescription: SeverityOff, RuleHardcodedDatasource: SeverityError},
+		},
+		{name: "Unknown rule", spec: "no-such-rule=error", wantErr: true},
+		{name: "Unknown severity", spec: "missing-description=fatal", wantErr: true},
+		{name: "Missing separator", spec: "missing-description", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSeverities(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSeverities() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseSeverities() = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("ParseSeverities()[%s] = %s, want %s", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{input: "30s", want: 30 * time.Second},
+		{input: "5m", want: 5 * time.Minute},
+		{input: "1d", want: 24 * time.Hour},
+		{input: "500ms", want: 500 * time.Millisecond},
+		{input: "abc", wantErr: true},
+		{input: "5x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseInterval(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseInterval() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseInterval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package mirror
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"grafana-db-exporter/internal/git"
+	"grafana-db-exporter/internal/logger"
+)
+
+// Mirror is an additional remote that receives the export after it was pushed to origin. Secrets can
+// be given inline or read from the environment variables named by TokenEnv and SSHKeyEnv.
+type Mirror struct {
+	Name               string `json:"name"`
+	URL                string `json:"url"`
+	Username           string `json:"username,omitempty"`
+	Token              string `json:"token,omitempty"`
+	TokenEnv           string `json:"tokenEnv,omitempty"`
+	SSHKeyPath         string `json:"sshKeyPath,omitempty"`
+	SSHKeyEnv          string `json:"sshKeyEnv,omitempty"`
+	SSHKeyPassword     string `json:"sshKeyPassword,omitempty"`
+	KnownHostsPath     string `json:"knownHostsPath,omitempty"`
+	AcceptUnknownHosts bool   `json:"acceptUnknownHosts,omitempty"`
+	Force              bool   `json:"force,omitempty"`
+	Required           bool   `json:"required,omitempty"`
+}
+
+func Load(path string) ([]Mirror, error) {
+	logger.Log.Debug().Str("path", path).Msg("Loading mirrors")
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mirrors: %w", err)
+	}
+
+	var mirrors []Mirror
+	if err := json.Unmarshal(raw, &mirrors); err != nil {
+		return nil, fmt.Errorf("failed to parse mirrors: %w", err)
+	}
+
+	names := make(map[string]bool, len(mirrors))
+	for i := range mirrors {
+		m := &mirrors[i]
+		if m.Name == "" || m.URL == "" {
+			return nil, fmt.Errorf("invalid mirror %d: name and url are required", i)
+		}
+		if m.Name == "origin" || names[m.Name] {
+			return nil, fmt.Errorf("invalid mirror %d: duplicate name %s", i, m.Name)
+		}
+		names[m.Name] = true
+
+		if m.TokenEnv != "" {
+			m.Token = os.Getenv(m.TokenEnv)
+		}
+		if m.Username == "" {
+			m.Username = "x-access-token"
+		}
+
+		if !git.IsHTTPURL(m.URL) {
+			if err := m.validateSSH(); err != nil {
+				return nil, fmt.Errorf("invalid mirror %s: %w", m.Name, err)
+			}
+		}
+	}
+	return mirrors, nil
+}
+
+// validateSSH checks the key and known hosts settings of an SSH mirror like the
+// configuration of origin is checked.
+func (m Mirror) validateSSH() error {
+	switch {
+	case m.SSHKeyEnv != "":
+		if os.Getenv(m.SSHKeyEnv) == "" {
+			return fmt.Errorf("environment variable %s is not set", m.SSHKeyEnv)
+		}
+	case m.SSHKeyPath == "":
+		return fmt.Errorf("sshKeyPath or sshKeyEnv is required for SSH mirrors")
+	default:
+		if _, err := os.Stat(m.SSHKeyPath); os.IsNotExist(err) {
+			return fmt.Errorf("SSH key file does not exist: %s", m.SSHKeyPath)
+		}
+	}
+
+	if !m.AcceptUnknownHosts && m.KnownHostsPath != "" {
+		if _, err := os.Stat(m.KnownHostsPath); os.IsNotExist(err) {
+			return fmt.Errorf("SSH known hosts file does not exist: %s", m.KnownHostsPath)
+		}
+	}
+	return nil
+}
+
+func (m Mirror) AuthOptions() git.AuthOptions {
+	opts := git.AuthOptions{
+		SSHKeyPath:         m.SSHKeyPath,
+		SSHKeyPassword:     m.SSHKeyPassword,
+		KnownHostsPath:     m.KnownHostsPath,
+		AcceptUnknownHosts: m.AcceptUnknownHosts,
+		Username:           m.Username,
+		Token:              m.Token,
+	}
+	if m.SSHKeyEnv != "" {
+		opts.SSHKey = os.Getenv(m.SSHKeyEnv)
+	}
+	return opts
+}
@@ -0,0 +1,70 @@
+package mirror
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeMirrors(t *testing.T, raw string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mirrors.json")
+	if err := os.WriteFile(path, []byte(raw), 0644); err != nil {
+		t.Fatalf("Failed to write mirrors: %v", err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Setenv("DR_TOKEN", "dr-secret")
+	t.Setenv("BACKUP_SSH_KEY", "key material")
+
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr bool
+	}{
+		{name: "Valid mirrors", raw: `[
+			{"name":"dr","url":"https://git.dr.example.com/org/iac.git","tokenEnv":"DR_TOKEN","required":true},
+			{"name":"backup","url":"git@backup.example.com:org/iac.git","sshKeyEnv":"BACKUP_SSH_KEY","acceptUnknownHosts":true,"force":true}
+		]`},
+		{name: "Missing URL", raw: `[{"name":"dr"}]`, wantErr: true},
+		{name: "Duplicate name", raw: `[{"name":"dr","url":"https://a.example.com/r.git"},{"name":"dr","url":"https://b.example.com/r.git"}]`, wantErr: true},
+		{name: "Origin name", raw: `[{"name":"origin","url":"https://a.example.com/r.git"}]`, wantErr: true},
+		{name: "SSH mirror without key", raw: `[{"name":"backup","url":"git@backup.example.com:org/iac.git"}]`, wantErr: true},
+		{name: "SSH key variable not set", raw: `[{"name":"backup","url":"git@backup.example.com:org/iac.git","sshKeyEnv":"UNSET_SSH_KEY"}]`, wantErr: true},
+		{name: "Missing SSH key file", raw: `[{"name":"backup","url":"git@backup.example.com:org/iac.git","sshKeyPath":"/non/existent/key"}]`, wantErr: true},
+		{name: "Missing known hosts file", raw: `[{"name":"backup","url":"ssh://git@backup.example.com/org/iac.git","sshKeyEnv":"BACKUP_SSH_KEY","knownHostsPath":"/non/existent/known_hosts"}]`, wantErr: true},
+		{name: "Invalid JSON", raw: `{`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mirrors, err := Load(writeMirrors(t, tt.raw))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(mirrors) != 2 {
+				t.Fatalf("Load() = %d mirrors, want 2", len(mirrors))
+			}
+			dr := mirrors[0].AuthOptions()
+			if dr.Token != "dr-secret" || dr.Username != "x-access-token" || !mirrors[0].Required {
+				t.Errorf("Load() dr = %+v", mirrors[0])
+			}
+			backup := mirrors[1].AuthOptions()
+			if backup.SSHKey != "key material" || !backup.AcceptUnknownHosts || !mirrors[1].Force {
+				t.Errorf("Load() backup = %+v", mirrors[1])
+			}
+		})
+	}
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("Load() should return an error for a missing file")
+	}
+}
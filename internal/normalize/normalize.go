@@ -0,0 +1,232 @@
+package normalize
+
+import (
+	"fmt"
+
+	"grafana-db-exporter/internal/logger"
+)
+
+type Options struct {
+	ResetTemplating bool
+	TimeFrom        string
+	TimeTo          string
+	StripRefresh    bool
+	ExpandRows      bool
+}
+
+func Dashboard(data map[string]interface{}, opts Options) {
+	if opts.ResetTemplating {
+		resetTemplating(data)
+	}
+
+	if opts.TimeFrom != "" || opts.TimeTo != "" {
+		resetTime(data, opts.TimeFrom, opts.TimeTo)
+	}
+
+	if opts.StripRefresh {
+		if _, ok := data["refresh"]; ok {
+			logger.Log.Debug().Msg("Stripping dashboard refresh override")
+			delete(data, "refresh")
+		}
+	}
+
+	if opts.ExpandRows {
+		expandRows(data)
+	}
+}
+
+func resetTemplating(data map[string]interface{}) {
+	templating, ok := data["templating"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	list, ok := templating["list"].([]interface{})
+	if !ok {
+		return
+	}
+
+	for _, item := range list {
+		variable, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		options, _ := variable["options"].([]interface{})
+		if len(options) == 0 {
+			continue
+		}
+
+		current, _ := variable["current"].(map[string]interface{})
+		selected := selectedValues(current)
+		if !hasOptions(options, selected) {
+			current = defaultCurrent(options)
+			variable["current"] = current
+			selected = selectedValues(current)
+		}
+
+		for _, opt := range options {
+			if option, ok := opt.(map[string]interface{}); ok {
+				isSelected := selected[fmt.Sprint(option["value"])]
+				if _, has := option["selected"]; has || isSelected {
+					option["selected"] = isSelected
+				}
+			}
+		}
+	}
+}
+
+// selectedValues returns the values of a current selection, which is a list for
+// multi-value variables.
+func selectedValues(current map[string]interface{}) map[string]bool {
+	values := make(map[string]bool)
+	switch v := current["value"].(type) {
+	case nil:
+	case []interface{}:
+		for _, item := range v {
+			values[fmt.Sprint(item)] = true
+		}
+	default:
+		values[fmt.Sprint(v)] = true
+	}
+	return values
+}
+
+func hasOptions(options []interface{}, values map[string]bool) bool {
+	if len(values) == 0 {
+		return false
+	}
+	known := make(map[string]bool, len(options))
+	for _, opt := range options {
+		if option, ok := opt.(map[string]interface{}); ok {
+			known[fmt.Sprint(option["value"])] = true
+		}
+	}
+	for value := range values {
+		if !known[value] {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultCurrent selects the first option, which Grafana also falls back to when
+// the saved selection is no longer one of the options.
+func defaultCurrent(options []interface{}) map[string]interface{} {
+	first, ok := options[0].(map[string]interface{})
+	if !ok {
+		return map[string]interface{}{}
+	}
+	return map[string]interface{}{
+		"selected": true,
+		"text":     first["text"],
+		"value":    first["value"],
+	}
+}
+
+func resetTime(data map[string]interface{}, from, to string) {
+	timeRange, ok := data["time"].(map[string]interface{})
+	if !ok {
+		timeRange = map[string]interface{}{}
+		data["time"] = timeRange
+	}
+
+	if from != "" {
+		timeRange["from"] = from
+	}
+	if to != "" {
+		timeRange["to"] = to
+	}
+}
+
+func expandRows(data map[string]interface{}) {
+	panels, ok := data["panels"].([]interface{})
+	if !ok {
+		return
+	}
+
+	// shift is how far panels below the expanded rows have to move down to make
+	// room for the panels lifted out of them.
+	shift := 0.0
+	expanded := make([]interface{}, 0, len(panels))
+	for _, p := range panels {
+		expanded = append(expanded, p)
+
+		panel, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if pos, ok := gridPos(panel); ok {
+			pos["y"] = number(pos["y"]) + shift
+		}
+		if panel["type"] != "row" {
+			continue
+		}
+
+		collapsed, _ := panel["collapsed"].(bool)
+		if !collapsed {
+			continue
+		}
+
+		if nested, ok := panel["panels"].([]interface{}); ok {
+			shift += placeBelowRow(panel, nested)
+			expanded = append(expanded, nested...)
+		}
+		panel["panels"] = []interface{}{}
+		panel["collapsed"] = false
+	}
+
+	data["panels"] = expanded
+}
+
+// placeBelowRow moves the nested panels of a collapsed row directly below it,
+// keeping their relative layout, and returns the height they take up.
+func placeBelowRow(row map[string]interface{}, nested []interface{}) float64 {
+	rowPos, ok := gridPos(row)
+	if !ok {
+		return 0
+	}
+	top := number(rowPos["y"]) + number(rowPos["h"])
+
+	minY := -1.0
+	for _, p := range nested {
+		if pos, ok := panelGridPos(p); ok && (minY < 0 || number(pos["y"]) < minY) {
+			minY = number(pos["y"])
+		}
+	}
+	if minY < 0 {
+		return 0
+	}
+
+	bottom := top
+	for _, p := range nested {
+		pos, ok := panelGridPos(p)
+		if !ok {
+			continue
+		}
+		y := number(pos["y"]) - minY + top
+		pos["y"] = y
+		if y+number(pos["h"]) > bottom {
+			bottom = y + number(pos["h"])
+		}
+	}
+	return bottom - top
+}
+
+func panelGridPos(p interface{}) (map[string]interface{}, bool) {
+	panel, ok := p.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return gridPos(panel)
+}
+
+func gridPos(panel map[string]interface{}) (map[string]interface{}, bool) {
+	pos, ok := panel["gridPos"].(map[string]interface{})
+	return pos, ok
+}
+
+func number(v interface{}) float64 {
+	f, _ := v.(float64)
+	return f
+}
@@ -0,0 +1,121 @@
+package normalize
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func parse(t *testing.T, raw string) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		t.Fatalf("Failed to parse test JSON: %v", err)
+	}
+	return m
+}
+
+func TestDashboard(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     Options
+		expected string
+	}{
+		{
+			name: "Reset templating keeps a saved selection that is still an option",
+			input: `{"templating":{"list":[{"name":"env","current":{"text":"prod","value":"prod"},
+				"options":[{"text":"dev","value":"dev","selected":true},{"text":"prod","value":"prod","selected":false}]}]}}`,
+			opts: Options{ResetTemplating: true},
+			expected: `{"templating":{"list":[{"name":"env","current":{"text":"prod","value":"prod"},
+				"options":[{"text":"dev","value":"dev","selected":false},{"text":"prod","value":"prod","selected":true}]}]}}`,
+		},
+		{
+			name: "Reset templating replaces a stale selection with the first option",
+			input: `{"templating":{"list":[{"name":"env","current":{"text":"qa","value":"qa"},
+				"options":[{"text":"dev","value":"dev","selected":false},{"text":"prod","value":"prod","selected":false}]}]}}`,
+			opts: Options{ResetTemplating: true},
+			expected: `{"templating":{"list":[{"name":"env","current":{"selected":true,"text":"dev","value":"dev"},
+				"options":[{"text":"dev","value":"dev","selected":true},{"text":"prod","value":"prod","selected":false}]}]}}`,
+		},
+		{
+			name: "Reset templating keeps a multi-value selection",
+			input: `{"templating":{"list":[{"name":"host","current":{"text":["a","c"],"value":["a","c"]},
+				"options":[{"text":"a","value":"a"},{"text":"b","value":"b","selected":true},{"text":"c","value":"c"}]}]}}`,
+			opts: Options{ResetTemplating: true},
+			expected: `{"templating":{"list":[{"name":"host","current":{"text":["a","c"],"value":["a","c"]},
+				"options":[{"text":"a","value":"a","selected":true},{"text":"b","value":"b","selected":false},{"text":"c","value":"c","selected":true}]}]}}`,
+		},
+		{
+			name:     "Reset templating without options",
+			input:    `{"templating":{"list":[{"name":"host","current":{"text":"a","value":"a"},"options":[]}]}}`,
+			opts:     Options{ResetTemplating: true},
+			expected: `{"templating":{"list":[{"name":"host","current":{"text":"a","value":"a"},"options":[]}]}}`,
+		},
+		{
+			name: "Keep templating when not resetting",
+			input: `{"templating":{"list":[{"name":"env","current":{"text":"qa","value":"qa"},
+				"options":[{"text":"dev","value":"dev","selected":true}]}]}}`,
+			opts: Options{},
+			expected: `{"templating":{"list":[{"name":"env","current":{"text":"qa","value":"qa"},
+				"options":[{"text":"dev","value":"dev","selected":true}]}]}}`,
+		},
+		{
+			name:     "Reset time range",
+			input:    `{"time":{"from":"now-7d","to":"now-1d"}}`,
+			opts:     Options{TimeFrom: "now-6h", TimeTo: "now"},
+			expected: `{"time":{"from":"now-6h","to":"now"}}`,
+		},
+		{
+			name:     "Add missing time range",
+			input:    `{}`,
+			opts:     Options{TimeFrom: "now-1h", TimeTo: "now"},
+			expected: `{"time":{"from":"now-1h","to":"now"}}`,
+		},
+		{
+			name:     "Strip refresh",
+			input:    `{"refresh":"5s","title":"t"}`,
+			opts:     Options{StripRefresh: true},
+			expected: `{"title":"t"}`,
+		},
+		{
+			name:     "Keep refresh when not stripping",
+			input:    `{"refresh":"5s"}`,
+			opts:     Options{},
+			expected: `{"refresh":"5s"}`,
+		},
+		{
+			name: "Expand collapsed rows",
+			input: `{"panels":[{"id":1,"type":"row","collapsed":true,"panels":[{"id":2},{"id":3}]},
+				{"id":4,"type":"row","collapsed":false,"panels":[]},{"id":5}]}`,
+			opts: Options{ExpandRows: true},
+			expected: `{"panels":[{"id":1,"type":"row","collapsed":false,"panels":[]},{"id":2},{"id":3},
+				{"id":4,"type":"row","collapsed":false,"panels":[]},{"id":5}]}`,
+		},
+		{
+			name: "Expand collapsed rows moves panels below",
+			input: `{"panels":[{"id":1,"type":"row","collapsed":true,"gridPos":{"x":0,"y":0,"w":24,"h":1},
+				"panels":[{"id":2,"gridPos":{"x":0,"y":30,"w":12,"h":8}},{"id":3,"gridPos":{"x":12,"y":30,"w":12,"h":10}}]},
+				{"id":4,"type":"row","collapsed":false,"gridPos":{"x":0,"y":1,"w":24,"h":1},"panels":[]},
+				{"id":5,"gridPos":{"x":0,"y":2,"w":24,"h":4}}]}`,
+			opts: Options{ExpandRows: true},
+			expected: `{"panels":[{"id":1,"type":"row","collapsed":false,"gridPos":{"x":0,"y":0,"w":24,"h":1},"panels":[]},
+				{"id":2,"gridPos":{"x":0,"y":1,"w":12,"h":8}},{"id":3,"gridPos":{"x":12,"y":1,"w":12,"h":10}},
+				{"id":4,"type":"row","collapsed":false,"gridPos":{"x":0,"y":11,"w":24,"h":1},"panels":[]},
+				{"id":5,"gridPos":{"x":0,"y":12,"w":24,"h":4}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := parse(t, tt.input)
+			Dashboard(data, tt.opts)
+
+			expected := parse(t, tt.expected)
+			if !reflect.DeepEqual(data, expected) {
+				got, _ := json.Marshal(data)
+				t.Errorf("Dashboard() = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
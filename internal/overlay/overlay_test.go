@@ -0,0 +1,197 @@
+package overlay
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func parse(t *testing.T, raw string) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		t.Fatalf("Failed to parse test JSON: %v", err)
+	}
+	return m
+}
+
+func parsePatch(t *testing.T, raw string) Patch {
+	t.Helper()
+	var p Patch
+	if err := json.Unmarshal([]byte(raw), &p); err != nil {
+		t.Fatalf("Failed to parse test patch: %v", err)
+	}
+	return p
+}
+
+func TestPatch_Apply(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		patch    string
+		expected string
+		wantErr  error
+	}{
+		{
+			name:     "Replace default data source",
+			doc:      `{"templating":{"list":[{"name":"ds","current":{"value":"prod"}}]}}`,
+			patch:    `[{"op":"replace","path":"/templating/list/0/current/value","value":"staging"}]`,
+			expected: `{"templating":{"list":[{"name":"ds","current":{"value":"staging"}}]}}`,
+		},
+		{
+			name:     "Add and append",
+			doc:      `{"tags":["a"]}`,
+			patch:    `[{"op":"add","path":"/editable","value":false},{"op":"add","path":"/tags/-","value":"b"},{"op":"add","path":"/tags/0","value":"z"}]`,
+			expected: `{"editable":false,"tags":["z","a","b"]}`,
+		},
+		{
+			name:     "Remove",
+			doc:      `{"id":1,"panels":[{"id":1},{"id":2}]}`,
+			patch:    `[{"op":"remove","path":"/id"},{"op":"remove","path":"/panels/0"}]`,
+			expected: `{"panels":[{"id":2}]}`,
+		},
+		{
+			name:     "Move and copy",
+			doc:      `{"a":{"b":1},"c":{}}`,
+			patch:    `[{"op":"copy","from":"/a/b","path":"/c/b"},{"op":"move","from":"/a","path":"/d"}]`,
+			expected: `{"c":{"b":1},"d":{"b":1}}`,
+		},
+		{
+			name:     "Escaped pointer tokens",
+			doc:      `{"a/b":{"c~d":1}}`,
+			patch:    `[{"op":"replace","path":"/a~1b/c~0d","value":2}]`,
+			expected: `{"a/b":{"c~d":2}}`,
+		},
+		{
+			name:     "Passing test",
+			doc:      `{"title":"A","refresh":null}`,
+			patch:    `[{"op":"test","path":"/title","value":"A"},{"op":"test","path":"/refresh","value":null}]`,
+			expected: `{"title":"A","refresh":null}`,
+		},
+		{
+			name:    "Failing test",
+			doc:     `{"title":"A"}`,
+			patch:   `[{"op":"test","path":"/title","value":"B"}]`,
+			wantErr: ErrTestFailed,
+		},
+		{
+			name:    "Replace missing path",
+			doc:     `{"panels":[]}`,
+			patch:   `[{"op":"replace","path":"/panels/3/datasource","value":"x"}]`,
+			wantErr: ErrPathNotFound,
+		},
+		{
+			name:    "Remove missing key",
+			doc:     `{}`,
+			patch:   `[{"op":"remove","path":"/links"}]`,
+			wantErr: ErrPathNotFound,
+		},
+		{
+			name:    "Add below missing parent",
+			doc:     `{}`,
+			patch:   `[{"op":"add","path":"/a/b","value":1}]`,
+			wantErr: ErrPathNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parse(t, tt.doc)
+			result, err := parsePatch(t, tt.patch).Apply(doc)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Apply() error = %v, want %v", err, tt.wantErr)
+				}
+				var patchErr *Error
+				if !errors.As(err, &patchErr) {
+					t.Errorf("Apply() error should be an *Error, got %T", err)
+				}
+				if !reflect.DeepEqual(doc, parse(t, tt.doc)) {
+					t.Errorf("Apply() modified the input document on failure")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(result, parse(t, tt.expected)) {
+				got, _ := json.Marshal(result)
+				t.Errorf("Apply() = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tempDir := t.TempDir()
+
+	dashboardPath := filepath.Join(tempDir, "dash1.json")
+	patchPath := PathFor(dashboardPath)
+	if patchPath != filepath.Join(tempDir, "dash1.patch.json") {
+		t.Errorf("PathFor() = %s, want dash1.patch.json", patchPath)
+	}
+	if !IsPatchFile(patchPath) || IsPatchFile(dashboardPath) {
+		t.Errorf("IsPatchFile() did not distinguish patch and dashboard files")
+	}
+
+	if _, err := Load(patchPath); err == nil {
+		t.Errorf("Load() expected error for missing patch file")
+	}
+
+	if err := os.WriteFile(patchPath, []byte(`[{"op":"remove","path":"/id"}]`), 0644); err != nil {
+		t.Fatalf("Failed to write patch file: %v", err)
+	}
+	patch, err := Load(patchPath)
+	if err != nil || len(patch) != 1 {
+		t.Errorf("Load() = (%v, %v), want one operation", patch, err)
+	}
+
+	if err := os.WriteFile(patchPath, []byte(`not json`), 0644); err != nil {
+		t.Fatalf("Failed to write patch file: %v", err)
+	}
+	if _, err := Load(patchPath); err == nil {
+		t.Errorf("Load() expected error for invalid patch file")
+	}
+}
+
+func TestFind(t *testing.T) {
+	tempDir := t.TempDir()
+	write := func(rel string) string {
+		t.Helper()
+		path := filepath.Join(tempDir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(`[]`), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		return path
+	}
+
+	if overlays, err := Find(filepath.Join(tempDir, "missing")); err != nil || len(overlays) != 0 {
+		t.Errorf("Find() for missing directory = (%v, %v), want empty", overlays, err)
+	}
+
+	write("General/dash1.json")
+	first := write("General/dash1.patch.json")
+	second := write("Team/Nested/dash2.patch.json")
+
+	overlays, err := Find(tempDir)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	want := map[string]string{"dash1": first, "dash2": second}
+	if !reflect.DeepEqual(overlays, want) {
+		t.Errorf("Find() = %v, want %v", overlays, want)
+	}
+
+	write("Team/dash1.patch.json")
+	if _, err := Find(tempDir); err == nil {
+		t.Errorf("Find() expected error for two overlays of the same dashboard")
+	}
+}
@@ -0,0 +1,275 @@
+package pullrequest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"grafana-db-exporter/internal/bitbucket"
+	"grafana-db-exporter/internal/git"
+	"grafana-db-exporter/internal/gitea"
+	"grafana-db-exporter/internal/github"
+	"grafana-db-exporter/internal/gitlab"
+	"grafana-db-exporter/internal/logger"
+)
+
+const (
+	ProviderAuto      = "auto"
+	ProviderGitHub    = "github"
+	ProviderGitLab    = "gitlab"
+	ProviderGitea     = "gitea"
+	ProviderForgejo   = "forgejo"
+	ProviderBitbucket = "bitbucket"
+)
+
+type Options struct {
+	SourceBranch       string
+	TargetBranch       string
+	Title              string
+	Description        string
+	Labels             []string
+	Assignees          []string
+	Reviewers          []string
+	Draft              bool
+	RemoveSourceBranch bool
+	AutoMerge          bool
+	// SupersedePrefix closes the other open pull requests into TargetBranch whose source branch starts
+	// with it, once the pull request from SourceBranch is open.
+	SupersedePrefix string
+}
+
+type Result struct {
+	ID         int
+	URL        string
+	Created    bool
+	Superseded []int
+}
+
+type Provider interface {
+	Name() string
+	Ensure(ctx context.Context, opts Options) (*Result, error)
+}
+
+func ValidProvider(name string) bool {
+	switch name {
+	case ProviderAuto, ProviderGitHub, ProviderGitLab, ProviderGitea, ProviderForgejo, ProviderBitbucket:
+		return true
+	}
+	return false
+}
+
+// Detect infers the provider from the host name of a remote URL.
+func Detect(remoteURL string) (string, error) {
+	host, _, err := git.ParseRemoteURL(remoteURL)
+	if err != nil {
+		return "", err
+	}
+
+	host = strings.ToLower(host)
+	switch {
+	case host == "bitbucket.org":
+		return "", fmt.Errorf("bitbucket cloud is not supported")
+	case strings.Contains(host, "github"):
+		return ProviderGitHub, nil
+	case strings.Contains(host, "gitlab"):
+		return ProviderGitLab, nil
+	case strings.Contains(host, "gitea"), strings.Contains(host, "forgejo"), host == "codeberg.org":
+		return ProviderGitea, nil
+	case strings.Contains(host, "bitbucket"):
+		return ProviderBitbucket, nil
+	}
+	return "", fmt.Errorf("cannot infer pull request provider from host %s, set PR_PROVIDER explicitly", host)
+}
+
+// New creates the provider for the repository at remoteURL. With ProviderAuto the provider is inferred from
+// the remote host, and an empty apiURL defaults to the provider's API on that host.
+func New(provider, remoteURL, apiURL, token string) (Provider, error) {
+	host, repoPath, err := git.ParseRemoteURL(remoteURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if provider == ProviderAuto {
+		if provider, err = Detect(remoteURL); err != nil {
+			return nil, err
+		}
+		logger.Log.Debug().Str("provider", provider).Str("host", host).Msg("Inferred pull request provider")
+	}
+
+	if apiURL == "" {
+		apiURL = defaultAPIURL(provider, host)
+	}
+
+	switch provider {
+	case ProviderGitHub:
+		client, err := github.New(apiURL, token, repoPath)
+		if err != nil {
+			return nil, err
+		}
+		return &githubProvider{client: client}, nil
+	case ProviderGitLab:
+		client, err := gitlab.New(apiURL, token, repoPath)
+		if err != nil {
+			return nil, err
+		}
+		return &gitlabProvider{client: client}, nil
+	case ProviderGitea, ProviderForgejo:
+		client, err := gitea.New(apiURL, token, repoPath)
+		if err != nil {
+			return nil, err
+		}
+		return &giteaProvider{client: client}, nil
+	case ProviderBitbucket:
+		client, err := bitbucket.New(apiURL, token, repoPath)
+		if err != nil {
+			return nil, err
+		}
+		return &bitbucketProvider{client: client}, nil
+	default:
+		return nil, fmt.Errorf("unsupported pull request provider: %s", provider)
+	}
+}
+
+func defaultAPIURL(provider, host string) string {
+	switch provider {
+	case ProviderGitHub:
+		if host == "github.com" {
+			return github.DefaultAPIURL
+		}
+		return fmt.Sprintf("https://%s/api/v3", host)
+	case ProviderGitLab:
+		return fmt.Sprintf("https://%s/api/v4", host)
+	case ProviderGitea, ProviderForgejo:
+		return fmt.Sprintf("https://%s/api/v1", host)
+	case ProviderBitbucket:
+		return fmt.Sprintf("https://%s/rest/api/1.0", host)
+	}
+	return ""
+}
+
+type githubProvider struct {
+	client *github.Client
+}
+
+func (p *githubProvider) Name() string {
+	return ProviderGitHub
+}
+
+func (p *githubProvider) Ensure(ctx context.Context, opts Options) (*Result, error) {
+	pr, created, err := p.client.EnsurePullRequest(ctx, github.PullRequestOptions{
+		Head:      opts.SourceBranch,
+		Base:      opts.TargetBranch,
+		Title:     opts.Title,
+		Body:      opts.Description,
+		Labels:    opts.Labels,
+		Reviewers: opts.Reviewers,
+		Draft:     opts.Draft,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{ID: pr.Number, URL: pr.URL, Created: created}
+	if opts.SupersedePrefix != "" {
+		if result.Superseded, err = p.client.CloseSupersededPullRequests(ctx, opts.SupersedePrefix, opts.TargetBranch, opts.SourceBranch); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+type gitlabProvider struct {
+	client *gitlab.Client
+}
+
+func (p *gitlabProvider) Name() string {
+	return ProviderGitLab
+}
+
+func (p *gitlabProvider) Ensure(ctx context.Context, opts Options) (*Result, error) {
+	mr, created, err := p.client.EnsureMergeRequest(ctx, gitlab.MergeRequestOptions{
+		SourceBranch:       opts.SourceBranch,
+		TargetBranch:       opts.TargetBranch,
+		Title:              opts.Title,
+		Description:        opts.Description,
+		Labels:             opts.Labels,
+		Assignees:          opts.Assignees,
+		Reviewers:          opts.Reviewers,
+		Draft:              opts.Draft,
+		RemoveSourceBranch: opts.RemoveSourceBranch,
+		AutoMerge:          opts.AutoMerge,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{ID: mr.IID, URL: mr.WebURL, Created: created}
+	if opts.SupersedePrefix != "" {
+		if result.Superseded, err = p.client.CloseSupersededMergeRequests(ctx, opts.SupersedePrefix, opts.TargetBranch, opts.SourceBranch); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+type giteaProvider struct {
+	client *gitea.Client
+}
+
+func (p *giteaProvider) Name() string {
+	return ProviderGitea
+}
+
+func (p *giteaProvider) Ensure(ctx context.Context, opts Options) (*Result, error) {
+	pr, created, err := p.client.EnsurePullRequest(ctx, gitea.PullRequestOptions{
+		Head:      opts.SourceBranch,
+		Base:      opts.TargetBranch,
+		Title:     opts.Title,
+		Body:      opts.Description,
+		Labels:    opts.Labels,
+		Assignees: opts.Assignees,
+		Reviewers: opts.Reviewers,
+		Draft:     opts.Draft,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{ID: pr.Number, URL: pr.URL, Created: created}
+	if opts.SupersedePrefix != "" {
+		if result.Superseded, err = p.client.CloseSupersededPullRequests(ctx, opts.SupersedePrefix, opts.TargetBranch, opts.SourceBranch); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+type bitbucketProvider struct {
+	client *bitbucket.Client
+}
+
+func (p *bitbucketProvider) Name() string {
+	return ProviderBitbucket
+}
+
+func (p *bitbucketProvider) Ensure(ctx context.Context, opts Options) (*Result, error) {
+	if len(opts.Labels) > 0 || len(opts.Assignees) > 0 {
+		logger.Log.Warn().Msg("Bitbucket Server does not support pull request labels or assignees, ignoring them")
+	}
+
+	pr, created, err := p.client.EnsurePullRequest(ctx, bitbucket.PullRequestOptions{
+		FromBranch:  opts.SourceBranch,
+		ToBranch:    opts.TargetBranch,
+		Title:       opts.Title,
+		Description: opts.Description,
+		Reviewers:   opts.Reviewers,
+		Draft:       opts.Draft,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{ID: pr.ID, URL: pr.URL(), Created: created}
+	if opts.SupersedePrefix != "" {
+		if result.Superseded, err = p.client.CloseSupersededPullRequests(ctx, opts.SupersedePrefix, opts.TargetBranch, opts.SourceBranch); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,153 @@
+package pullrequest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{url: "git@github.com:org/repo.git", want: ProviderGitHub},
+		{url: "git@github.example.com:org/repo.git", want: ProviderGitHub},
+		{url: "ssh://git@gitlab.example.com:2222/group/repo.git", want: ProviderGitLab},
+		{url: "git@codeberg.org:org/repo.git", want: ProviderGitea},
+		{url: "https://forgejo.example.com/org/repo.git", want: ProviderGitea},
+		{url: "ssh://git@bitbucket.example.com:7999/OPS/repo.git", want: ProviderBitbucket},
+		{url: "git@bitbucket.org:org/repo.git", wantErr: true},
+		{url: "git@git.example.com:org/repo.git", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, err := Detect(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Detect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Detect() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultAPIURL(t *testing.T) {
+	tests := []struct {
+		provider string
+		host     string
+		want     string
+	}{
+		{ProviderGitHub, "github.com", "https://api.github.com"},
+		{ProviderGitHub, "github.example.com", "https://github.example.com/api/v3"},
+		{ProviderGitLab, "gitlab.example.com", "https://gitlab.example.com/api/v4"},
+		{ProviderForgejo, "codeberg.org", "https://codeberg.org/api/v1"},
+		{ProviderBitbucket, "bitbucket.example.com", "https://bitbucket.example.com/rest/api/1.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider+" "+tt.host, func(t *testing.T) {
+			if got := defaultAPIURL(tt.provider, tt.host); got != tt.want {
+				t.Errorf("defaultAPIURL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		provider  string
+		remoteURL string
+		wantName  string
+		wantErr   bool
+	}{
+		{name: "Explicit GitHub", provider: ProviderGitHub, remoteURL: "git@git.example.com:org/repo.git", wantName: ProviderGitHub},
+		{name: "Inferred GitLab", provider: ProviderAuto, remoteURL: "git@gitlab.example.com:group/sub/repo.git", wantName: ProviderGitLab},
+		{name: "Forgejo alias", provider: ProviderForgejo, remoteURL: "git@codeberg.org:org/repo.git", wantName: ProviderGitea},
+		{name: "Inferred Bitbucket", provider: ProviderAuto, remoteURL: "ssh://git@bitbucket.example.com:7999/OPS/repo.git", wantName: ProviderBitbucket},
+		{name: "Unknown host", provider: ProviderAuto, remoteURL: "git@git.example.com:org/repo.git", wantErr: true},
+		{name: "Unknown provider", provider: "svn", remoteURL: "git@github.com:org/repo.git", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.provider, tt.remoteURL, "", "token")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && p.Name() != tt.wantName {
+				t.Errorf("New() provider = %s, want %s", p.Name(), tt.wantName)
+			}
+		})
+	}
+}
+
+func TestProvider_Ensure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte(`[]`))
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"number":1,"html_url":"https://github.example.com/org/repo/pull/1"}`))
+		}
+	}))
+	defer server.Close()
+
+	p, err := New(ProviderGitHub, "git@github.example.com:org/repo.git", server.URL, "token")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	result, err := p.Ensure(context.Background(), Options{SourceBranch: "export", TargetBranch: "main", Title: "t"})
+	if err != nil {
+		t.Fatalf("Ensure() error = %v", err)
+	}
+	if result.ID != 1 || !result.Created || result.URL != "https://github.example.com/org/repo/pull/1" {
+		t.Errorf("Ensure() = %+v", result)
+	}
+}
+
+func TestProvider_Ensure_Supersede(t *testing.T) {
+	var closed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			if r.URL.Query().Get("head") != "" {
+				_, _ = w.Write([]byte(`[]`))
+				return
+			}
+			_, _ = w.Write([]byte(`[{"number":3,"head":{"ref":"export-2","label":"org:export-2"}},{"number":2,"head":{"ref":"export-1","label":"org:export-1"}}]`))
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"number":3,"html_url":"https://github.example.com/org/repo/pull/3"}`))
+		case http.MethodPatch:
+			closed = append(closed, r.URL.Path)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	p, err := New(ProviderGitHub, "git@github.example.com:org/repo.git", server.URL, "token")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	result, err := p.Ensure(context.Background(), Options{SourceBranch: "export-2", TargetBranch: "main", Title: "t", SupersedePrefix: "export-"})
+	if err != nil {
+		t.Fatalf("Ensure() error = %v", err)
+	}
+	if result.ID != 3 || len(result.Superseded) != 1 || result.Superseded[0] != 2 {
+		t.Errorf("Ensure() = %+v, want #3 superseding #2", result)
+	}
+	if len(closed) != 1 || closed[0] != "/repos/org/repo/pulls/2" {
+		t.Errorf("Ensure() closed %v, want only #2", closed)
+	}
+}
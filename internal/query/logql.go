@@ -0,0 +1,29 @@
+package query
+
+import (
+	"github.com/grafana/loki/v3/pkg/logql/syntax"
+)
+
+func validateLogQL(expr string) error {
+	_, err := syntax.ParseExpr(ExpandVariables(expr))
+	return err
+}
+
+// StreamLabels returns the sorted label names used in the stream selectors of a LogQL expression.
+func StreamLabels(expr string) ([]string, error) {
+	parsed, err := syntax.ParseExpr(ExpandVariables(expr))
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	parsed.Walk(func(e syntax.Expr) bool {
+		if selector, ok := e.(*syntax.MatchersExpr); ok {
+			for _, m := range selector.Matchers() {
+				seen[m.Name] = true
+			}
+		}
+		return true
+	})
+	return sortedKeys(seen), nil
+}
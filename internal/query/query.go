@@ -0,0 +1,458 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"grafana-db-exporter/internal/grafana"
+)
+
+const (
+	LanguagePromQL = "promql"
+	LanguageLogQL  = "logql"
+	LanguageSQL    = "sql"
+)
+
+var languageByDatasourceType = map[string]string{
+	"prometheus":                    LanguagePromQL,
+	"loki":                          LanguageLogQL,
+	"mysql":                         LanguageSQL,
+	"postgres":                      LanguageSQL,
+	"grafana-postgresql-datasource": LanguageSQL,
+	"mssql":                         LanguageSQL,
+}
+
+var queryFieldByLanguage = map[string]string{
+	LanguagePromQL: "expr",
+	LanguageLogQL:  "expr",
+	LanguageSQL:    "rawSql",
+}
+
+var promQLParser = parser.NewParser(parser.Options{})
+
+type Query struct {
+	Path           string `json:"path"`
+	PanelID        *int   `json:"panelID,omitempty"`
+	PanelTitle     string `json:"panelTitle,omitempty"`
+	DatasourceType string `json:"datasourceType"`
+	Language       string `json:"language"`
+	Expr           string `json:"expr"`
+}
+
+type Issue struct {
+	DashboardUID string `json:"dashboardUID"`
+	Path         string `json:"path"`
+	PanelID      *int   `json:"panelID,omitempty"`
+	PanelTitle   string `json:"panelTitle,omitempty"`
+	Language     string `json:"language"`
+	Query        string `json:"query"`
+	Message      string `json:"message"`
+}
+
+// Extract returns the queries of all panel targets whose data source type is known.
+func Extract(data map[string]interface{}) []Query {
+	var queries []Query
+	grafana.ForEachPanel(data, func(path string, panel map[string]interface{}) {
+		panelType := datasourceType(panel["datasource"])
+
+		targets, _ := panel["targets"].([]interface{})
+		for i, t := range targets {
+			target, ok := t.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			dsType := datasourceType(target["datasource"])
+			if dsType == "" {
+				dsType = panelType
+			}
+			language, ok := languageByDatasourceType[dsType]
+			if !ok {
+				continue
+			}
+
+			field := queryFieldByLanguage[language]
+			expr, _ := target[field].(string)
+			if strings.TrimSpace(expr) == "" {
+				continue
+			}
+
+			q := Query{
+				Path:           fmt.Sprintf("%s.targets[%d].%s", path, i, field),
+				DatasourceType: dsType,
+				Language:       language,
+				Expr:           expr,
+			}
+			if id, ok := grafana.PanelID(panel); ok {
+				q.PanelID = &id
+			}
+			q.PanelTitle, _ = panel["title"].(string)
+			queries = append(queries, q)
+		}
+	})
+	return queries
+}
+
+// ValidateDashboard validates the PromQL, LogQL and SQL queries of a dashboard.
+func ValidateDashboard(uid string, data map[string]interface{}) []Issue {
+	var issues []Issue
+	for _, q := range Extract(data) {
+		if err := Validate(q.Language, q.Expr); err != nil {
+			issues = append(issues, Issue{
+				DashboardUID: uid,
+				Path:         q.Path,
+				PanelID:      q.PanelID,
+				PanelTitle:   q.PanelTitle,
+				Language:     q.Language,
+				Query:        q.Expr,
+				Message:      err.Error(),
+			})
+		}
+	}
+	return issues
+}
+
+func Validate(language, expr string) error {
+	switch language {
+	case LanguagePromQL:
+		_, err := promQLParser.ParseExpr(ExpandVariables(expr))
+		return err
+	case LanguageLogQL:
+		return validateLogQL(expr)
+	case LanguageSQL:
+		return validateSQL(expandSQLMacros(expr))
+	default:
+		return fmt.Errorf("unsupported query language %q", language)
+	}
+}
+
+// Metrics returns the sorted metric names referenced by a PromQL expression.
+func Metrics(expr string) ([]string, error) {
+	parsed, err := promQLParser.ParseExpr(ExpandVariables(expr))
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	parser.Inspect(parsed, func(node parser.Node, _ []parser.Node) error {
+		vs, ok := node.(*parser.VectorSelector)
+		if !ok {
+			return nil
+		}
+		name := vs.Name
+		if name == "" {
+			for _, m := range vs.LabelMatchers {
+				if m.Name == "__name__" && m.Type == labels.MatchEqual {
+					name = m.Value
+				}
+			}
+		}
+		if name != "" && name != variablePlaceholder {
+			seen[name] = true
+		}
+		return nil
+	})
+
+	return sortedKeys(seen), nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func datasourceType(ds interface{}) string {
+	if m, ok := ds.(map[string]interface{}); ok {
+		t, _ := m["type"].(string)
+		return t
+	}
+	return ""
+}
+
+const variablePlaceholder = "var"
+
+var variablePattern = regexp.MustCompile(`\$\{([A-Za-z0-9_]+)(?::[^}]*)?\}|\$([A-Za-z0-9_]+)|\[\[([A-Za-z0-9_]+)(?::[^\]]*)?\]\]`)
+
+// ExpandVariables replaces Grafana template variables with placeholders that keep the query parseable:
+// durations inside range selectors and after offset, numbers for scalar function and aggregation
+// parameters such as topk($k, ...), plain identifiers elsewhere.
+func ExpandVariables(expr string) string {
+	matches := variablePattern.FindAllStringSubmatchIndex(expr, -1)
+	if len(matches) == 0 {
+		return expr
+	}
+
+	inQuote, inBracket := scanContext(expr)
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		name := ""
+		for g := 1; g < len(m)/2; g++ {
+			if m[2*g] >= 0 {
+				name = expr[m[2*g]:m[2*g+1]]
+				break
+			}
+		}
+
+		b.WriteString(expr[last:m[0]])
+		afterOffset := strings.HasSuffix(strings.TrimSpace(strings.ToLower(expr[:m[0]])), "offset")
+		ph := placeholder(name, inQuote[m[0]], inBracket[m[0]] || afterOffset)
+		if ph == variablePlaceholder && !inQuote[m[0]] && scalarArgument(expr, m[0], m[1], inQuote) {
+			ph = "1"
+		}
+		b.WriteString(ph)
+		last = m[1]
+	}
+	b.WriteString(expr[last:])
+	return b.String()
+}
+
+func placeholder(name string, quoted, duration bool) string {
+	switch {
+	case quoted:
+		return variablePlaceholder
+	case duration:
+		return "1m"
+	case strings.HasPrefix(name, "__") && strings.HasSuffix(name, "_ms"):
+		return "60000"
+	case strings.HasPrefix(name, "__") && strings.HasSuffix(name, "_s"):
+		return "60"
+	case strings.HasPrefix(name, "__"):
+		return "1m"
+	default:
+		return variablePlaceholder
+	}
+}
+
+// scalarParamAggregators take a scalar as their first parameter, e.g. topk(5, ...).
+var scalarParamAggregators = map[string]bool{
+	"topk":        true,
+	"bottomk":     true,
+	"quantile":    true,
+	"limitk":      true,
+	"limit_ratio": true,
+}
+
+// scalarArgument reports whether expr[start:end] is a whole function or aggregation argument that
+// must be a scalar.
+func scalarArgument(expr string, start, end int, inQuote []bool) bool {
+	before := strings.TrimRight(expr[:start], " \t\n")
+	after := strings.TrimLeft(expr[end:], " \t\n")
+	if before == "" || after == "" || !strings.ContainsRune(",)", rune(after[0])) {
+		return false
+	}
+
+	var open, index int
+	switch before[len(before)-1] {
+	case '(':
+		open = len(before) - 1
+	case ',':
+		var commas int
+		open, commas = openingParen(expr, len(before)-1, inQuote)
+		index = commas + 1
+	default:
+		return false
+	}
+	if open < 0 {
+		return false
+	}
+
+	name := strings.TrimRight(expr[:open], " \t\n")
+	if strings.HasSuffix(name, ")") {
+		// Skip a by (...) or without (...) clause written before the parameters.
+		clause, _ := openingParen(expr, len(name)-1, inQuote)
+		if clause < 0 {
+			return false
+		}
+		name = strings.TrimRight(expr[:clause], " \t\n")
+		name = strings.TrimRight(strings.TrimSuffix(strings.TrimSuffix(name, "by"), "without"), " \t\n")
+	}
+	name = strings.ToLower(name[strings.LastIndexFunc(name, func(r rune) bool {
+		return r != '_' && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9')
+	})+1:])
+
+	if scalarParamAggregators[name] {
+		return index == 0
+	}
+	f, ok := parser.Functions[name]
+	if !ok || len(f.ArgTypes) == 0 {
+		return false
+	}
+	if index >= len(f.ArgTypes) {
+		if f.Variadic == 0 {
+			return false
+		}
+		index = len(f.ArgTypes) - 1
+	}
+	return f.ArgTypes[index] == parser.ValueTypeScalar
+}
+
+// openingParen scans backwards from expr[pos] and returns the position of the unmatched opening
+// parenthesis and the number of top-level commas in between, or -1 if there is none.
+func openingParen(expr string, pos int, inQuote []bool) (int, int) {
+	depth, commas := 0, 0
+	for i := pos - 1; i >= 0; i-- {
+		if inQuote[i] {
+			continue
+		}
+		switch expr[i] {
+		case ')', ']', '}':
+			depth++
+		case '(', '[', '{':
+			if depth == 0 {
+				if expr[i] != '(' {
+					return -1, 0
+				}
+				return i, commas
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				commas++
+			}
+		}
+	}
+	return -1, 0
+}
+
+// scanContext reports for every byte of expr whether it is inside a string literal or a [...] range.
+func scanContext(expr string) ([]bool, []bool) {
+	inQuote := make([]bool, len(expr)+1)
+	inBracket := make([]bool, len(expr)+1)
+
+	var quote byte
+	depth := 0
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		inQuote[i] = quote != 0
+		inBracket[i] = depth > 0
+
+		switch {
+		case quote != 0:
+			if c == '\\' && quote != '`' {
+				i++
+				if i < len(expr) {
+					inQuote[i] = true
+					inBracket[i] = depth > 0
+				}
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']' && depth > 0:
+			depth--
+		}
+	}
+	return inQuote, inBracket
+}
+
+var sqlMacroPattern = regexp.MustCompile(`\$__[A-Za-z]+\([^()]*\)`)
+
+func expandSQLMacros(expr string) string {
+	expr = sqlMacroPattern.ReplaceAllString(expr, "1")
+	return variablePattern.ReplaceAllString(expr, variablePlaceholder)
+}
+
+var (
+	sqlStatementPattern   = regexp.MustCompile(`(?i)^\(*\s*(select|with|show|describe|desc|explain|values)\b`)
+	sqlDanglingComma      = regexp.MustCompile(`(?i),\s*(from|where|group|order|limit|having|\))(\b|$)`)
+	sqlEmptySelect        = regexp.MustCompile(`(?i)\bselect\s*(from\b|$)`)
+	sqlTrailingSemicolons = regexp.MustCompile(`;\s*$`)
+	sqlMultipleStatements = regexp.MustCompile(`;\s*\S`)
+)
+
+// validateSQL performs basic syntax checks: statement keyword, balanced quotes and parentheses, and stray commas.
+func validateSQL(expr string) error {
+	stripped, err := stripSQL(expr)
+	if err != nil {
+		return err
+	}
+	stripped = sqlTrailingSemicolons.ReplaceAllString(strings.TrimSpace(stripped), "")
+
+	if stripped == "" {
+		return fmt.Errorf("empty query")
+	}
+	if !sqlStatementPattern.MatchString(stripped) {
+		return fmt.Errorf("unsupported statement %q", strings.Fields(stripped)[0])
+	}
+	if sqlMultipleStatements.MatchString(stripped) {
+		return fmt.Errorf("multiple statements are not supported")
+	}
+
+	depth := 0
+	for _, c := range stripped {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return fmt.Errorf("unexpected closing parenthesis")
+			}
+		}
+	}
+	if depth != 0 {
+		return fmt.Errorf("unclosed parenthesis")
+	}
+
+	if m := sqlDanglingComma.FindString(stripped); m != "" {
+		return fmt.Errorf("unexpected comma before %q", strings.TrimSpace(strings.TrimPrefix(m, ",")))
+	}
+	if sqlEmptySelect.MatchString(stripped) {
+		return fmt.Errorf("SELECT without columns")
+	}
+	return nil
+}
+
+// stripSQL replaces quoted literals and identifiers with a placeholder so their contents are not
+// checked, and comments with a space. Both are handled in one pass, so quotes inside comments and
+// comment markers inside strings are ignored.
+func stripSQL(expr string) (string, error) {
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+				b.WriteString("x")
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case strings.HasPrefix(expr[i:], "--"):
+			end := strings.IndexByte(expr[i:], '\n')
+			if end < 0 {
+				end = len(expr) - i
+			}
+			i += end - 1
+			b.WriteString(" ")
+		case strings.HasPrefix(expr[i:], "/*"):
+			end := strings.Index(expr[i+2:], "*/")
+			if end < 0 {
+				return "", fmt.Errorf("unterminated block comment")
+			}
+			i += end + 3
+			b.WriteString(" ")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if quote != 0 {
+		return "", fmt.Errorf("unterminated quoted string")
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,213 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func parse(t *testing.T, raw string) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		t.Fatalf("Failed to parse test JSON: %v", err)
+	}
+	return m
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		expr     string
+		wantErr  bool
+	}{
+		{name: "PromQL valid", language: LanguagePromQL, expr: `sum by (job) (rate(http_requests_total{code=~"5.."}[5m]))`},
+		{name: "PromQL with variables", language: LanguagePromQL, expr: `rate(http_requests_total{job="$job", env=~"${env:regex}"}[$__rate_interval] offset $offset)`},
+		{name: "PromQL range variable", language: LanguagePromQL, expr: `increase(up[$__range]) / $__range_s`},
+		{name: "PromQL topk variable", language: LanguagePromQL, expr: `topk($topk, up)`},
+		{name: "PromQL topk variable with grouping", language: LanguagePromQL, expr: `topk by (job) ($topk, sum by (job, instance) (up))`},
+		{name: "PromQL quantile variables", language: LanguagePromQL, expr: `histogram_quantile($q, sum by (le) (rate(http_duration_seconds_bucket{job="$job"}[5m])))`},
+		{name: "PromQL quantile over time variable", language: LanguagePromQL, expr: `quantile_over_time(${quantile}, up[$__range])`},
+		{name: "PromQL clamp variables", language: LanguagePromQL, expr: `clamp(rate(x[5m]), $min, $max) > $threshold`},
+		{name: "PromQL vector variable", language: LanguagePromQL, expr: `vector($value)`},
+		{name: "PromQL metric variable", language: LanguagePromQL, expr: `topk(5, $metric)`},
+		{name: "PromQL unclosed parenthesis", language: LanguagePromQL, expr: `rate(http_requests_total[5m]`, wantErr: true},
+		{name: "PromQL bad range", language: LanguagePromQL, expr: `rate(http_requests_total[5x])`, wantErr: true},
+
+		{name: "LogQL valid", language: LanguageLogQL, expr: `sum by (level) (count_over_time({app="api"} |= "error" | json | level!="debug" [5m]))`},
+		{name: "LogQL with variables", language: LanguageLogQL, expr: `topk($k, sum by (pod) (rate({namespace="$namespace", app=~"${app:regex}"} |~ "$search" [$__interval])))`},
+		{name: "LogQL quantile variable", language: LanguageLogQL, expr: `quantile_over_time($q, {app="api"} | logfmt | unwrap duration [$__range]) by (pod)`},
+		{name: "LogQL unquoted filter", language: LanguageLogQL, expr: `{app="api"} |= broken`, wantErr: true},
+		{name: "LogQL unclosed selector", language: LanguageLogQL, expr: `{app="api"`, wantErr: true},
+		{name: "LogQL missing range", language: LanguageLogQL, expr: `rate({app="api"})`, wantErr: true},
+
+		{name: "SQL valid", language: LanguageSQL, expr: `SELECT $__timeGroupAlias(created_at, '5m'), count(*) AS "value" FROM orders WHERE $__timeFilter(created_at) AND region IN ($region) GROUP BY 1 ORDER BY 1`},
+		{name: "SQL with CTE and comments", language: LanguageSQL, expr: "-- recent\nWITH x AS (SELECT 1 AS a) /* inline */ SELECT a FROM x;"},
+		{name: "SQL quoted parentheses", language: LanguageSQL, expr: `SELECT ')' AS paren, 'it''s' AS quote`},
+		{name: "SQL apostrophe in line comment", language: LanguageSQL, expr: `SELECT 1 -- don't`},
+		{name: "SQL quote in block comment", language: LanguageSQL, expr: "SELECT /* it's \"fine\" */ a FROM t"},
+		{name: "SQL comment markers in string", language: LanguageSQL, expr: `SELECT '--' AS a, '/*' AS b FROM t`},
+		{name: "SQL unterminated block comment", language: LanguageSQL, expr: `SELECT a /* FROM t`, wantErr: true},
+		{name: "SQL unterminated string", language: LanguageSQL, expr: `SELECT 'abc FROM t`, wantErr: true},
+		{name: "SQL unbalanced parentheses", language: LanguageSQL, expr: `SELECT count(* FROM t`, wantErr: true},
+		{name: "SQL dangling comma", language: LanguageSQL, expr: `SELECT a, b, FROM t`, wantErr: true},
+		{name: "SQL empty select", language: LanguageSQL, expr: `SELECT FROM t`, wantErr: true},
+		{name: "SQL unsupported statement", language: LanguageSQL, expr: `DELETE FROM t`, wantErr: true},
+		{name: "SQL multiple statements", language: LanguageSQL, expr: `SELECT 1; SELECT 2`, wantErr: true},
+
+		{name: "Unknown language", language: "flux", expr: `from(bucket: "x")`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.language, tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	data := parse(t, `{"panels":[
+		{"id":1,"title":"Requests","datasource":{"type":"prometheus","uid":"${ds}"},
+			"targets":[{"expr":"up"},{"expr":""},{"datasource":{"type":"loki","uid":"logs"},"expr":"{app=\"a\"}"}]},
+		{"id":2,"title":"Orders","datasource":{"type":"grafana-postgresql-datasource","uid":"pg"},"targets":[{"rawSql":"SELECT 1"}]},
+		{"id":3,"title":"Unknown","datasource":{"type":"influxdb","uid":"i"},"targets":[{"query":"SELECT 1"}]},
+		{"id":4,"type":"row","panels":[{"id":5,"title":"Nested","datasource":{"type":"prometheus"},"targets":[{"expr":"rate(x[5m])"}]}]}]}`)
+
+	queries := Extract(data)
+
+	expected := []struct {
+		path     string
+		language string
+		panelID  int
+	}{
+		{"$.panels[0].targets[0].expr", LanguagePromQL, 1},
+		{"$.panels[0].targets[2].expr", LanguageLogQL, 1},
+		{"$.panels[1].targets[0].rawSql", LanguageSQL, 2},
+		{"$.panels[3].panels[0].targets[0].expr", LanguagePromQL, 5},
+	}
+	if len(queries) != len(expected) {
+		t.Fatalf("Extract() returned %d queries, want %d: %+v", len(queries), len(expected), queries)
+	}
+	for i, want := range expected {
+		q := queries[i]
+		if q.Path != want.path || q.Language != want.language || q.PanelID == nil || *q.PanelID != want.panelID {
+			t.Errorf("Extract()[%d] = %+v, want path %s, language %s, panel %d", i, q, want.path, want.language, want.panelID)
+		}
+	}
+}
+
+func TestValidateDashboard(t *testing.T) {
+	data := parse(t, `{"panels":[{"id":7,"title":"Broken","datasource":{"type":"prometheus","uid":"p"},
+		"targets":[{"expr":"up"},{"expr":"rate(up[5m]"},{"datasource":{"type":"loki"},"expr":"{app=\"api\"} |= broken"}]}]}`)
+
+	issues := ValidateDashboard("dash1", data)
+	if len(issues) != 2 {
+		t.Fatalf("ValidateDashboard() returned %d issues, want 2: %+v", len(issues), issues)
+	}
+
+	issue := issues[0]
+	if issue.DashboardUID != "dash1" || issue.Path != "$.panels[0].targets[1].expr" || issue.PanelTitle != "Broken" || issue.Message == "" {
+		t.Errorf("ValidateDashboard() issue = %+v", issue)
+	}
+	if issues[1].Path != "$.panels[0].targets[2].expr" || issues[1].Language != LanguageLogQL {
+		t.Errorf("ValidateDashboard() LogQL issue = %+v", issues[1])
+	}
+}
+
+func TestExpandVariables(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`up{job="$job"}`, `up{job="var"}`},
+		{`rate(x[$__rate_interval])`, `rate(x[1m])`},
+		{`rate(x[${interval}])`, `rate(x[1m])`},
+		{`x offset $shift`, `x offset 1m`},
+		{`topk(5, $metric)`, `topk(5, var)`},
+		{`topk($k, up)`, `topk(1, up)`},
+		{`bottomk without (pod) ($k, up)`, `bottomk without (pod) (1, up)`},
+		{`histogram_quantile($q, rate(x[5m]))`, `histogram_quantile(1, rate(x[5m]))`},
+		{`round(x, $to)`, `round(x, 1)`},
+		{`label_replace(x, "a", "$1", "b", "(.*)")`, `label_replace(x, "a", "var", "b", "(.*)")`},
+		{`sum($metric)`, `sum(var)`},
+		{`x / $__range_s`, `x / 60`},
+		{`x{a="[[var]]"}`, `x{a="var"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := ExpandVariables(tt.input); got != tt.expected {
+				t.Errorf("ExpandVariables() = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected []string
+		wantErr  bool
+	}{
+		{expr: `sum(rate(b_total[5m])) / sum(rate(a_total[5m]))`, expected: []string{"a_total", "b_total"}},
+		{expr: `{__name__="up", job="x"}`, expected: []string{"up"}},
+		{expr: `rate($metric[$__rate_interval])`, expected: []string{}},
+		{expr: `vector(1)`, expected: []string{}},
+		{expr: `rate(up[5m]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := Metrics(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Metrics() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Metrics() = %v, want %v", got, tt.expected)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("Metrics()[%d] = %s, want %s", i, got[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStreamLabels(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected []string
+		wantErr  bool
+	}{
+		{
+			expr:     `sum(rate({namespace="prod", app=~"api|web"} | json | level="error" [5m])) + sum(rate({cluster="$cluster"}[5m]))`,
+			expected: []string{"app", "cluster", "namespace"},
+		},
+		{expr: `{app="api"} | line_format "{{.msg}}"`, expected: []string{"app"}},
+		{expr: "{job=`x`} != `debug`", expected: []string{"job"}},
+		{expr: "{job=`x`,}", wantErr: true},
+		{expr: `{app="api"`, wantErr: true},
+		{expr: `{app=api}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := StreamLabels(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StreamLabels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("StreamLabels() = %v, want %v", got, tt.expected)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("StreamLabels()[%d] = %s, want %s", i, got[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
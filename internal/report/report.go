@@ -0,0 +1,52 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"grafana-db-exporter/internal/lint"
+	"grafana-db-exporter/internal/query"
+	"grafana-db-exporter/internal/secrets"
+)
+
+type Report struct {
+	GeneratedAt time.Time         `json:"generatedAt"`
+	Dashboards  int               `json:"dashboards"`
+	Lint        []lint.Violation  `json:"lint"`
+	Queries     []query.Issue     `json:"queries"`
+	Secrets     []secrets.Finding `json:"secrets,omitempty"`
+	Pushes      []PushResult      `json:"pushes,omitempty"`
+	Conflicts   []string          `json:"conflicts,omitempty"`
+	Tag         string            `json:"tag,omitempty"`
+}
+
+type PushResult struct {
+	Remote string `json:"remote"`
+	URL    string `json:"url"`
+	Branch string `json:"branch"`
+	Pushed bool   `json:"pushed"`
+	Error  string `json:"error,omitempty"`
+}
+
+func New() *Report {
+	return &Report{GeneratedAt: time.Now().UTC(), Lint: []lint.Violation{}, Queries: []query.Issue{}}
+}
+
+func (r *Report) Write(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	data = append(data, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
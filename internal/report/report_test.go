@@ -0,0 +1,59 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"grafana-db-exporter/internal/lint"
+	"grafana-db-exporter/internal/query"
+	"grafana-db-exporter/internal/secrets"
+)
+
+func TestReport_Write(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reports", "run.json")
+
+	r := New()
+	r.Dashboards = 2
+	r.Lint = append(r.Lint, lint.Violation{
+		DashboardUID: "dash1",
+		Rule:         lint.RuleMissingDescription,
+		Severity:     lint.SeverityInfo,
+		Path:         "$.description",
+		Message:      "dashboard has no description",
+	})
+
+	r.Queries = append(r.Queries, query.Issue{
+		DashboardUID: "dash2",
+		Path:         "$.panels[0].targets[0].expr",
+		Language:     query.LanguagePromQL,
+		Query:        "rate(up[5m]",
+		Message:      "unclosed left parenthesis",
+	})
+
+	r.Secrets = append(r.Secrets, secrets.Finding{
+		DashboardUID: "dash3",
+		Path:         "$.links[0].url",
+		Rule:         "url-credentials",
+	})
+
+	if err := r.Write(path); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+
+	var decoded Report
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if decoded.Dashboards != 2 || len(decoded.Lint) != 1 || decoded.Lint[0].Rule != lint.RuleMissingDescription ||
+		len(decoded.Queries) != 1 || decoded.Queries[0].DashboardUID != "dash2" ||
+		len(decoded.Secrets) != 1 || decoded.Secrets[0].DashboardUID != "dash3" || decoded.Secrets[0].Rule != "url-credentials" {
+		t.Errorf("Write() produced unexpected report: %s", raw)
+	}
+}
@@ -0,0 +1,174 @@
+package secrets
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"grafana-db-exporter/internal/logger"
+)
+
+const (
+	ActionRedact = "redact"
+	ActionSkip   = "skip"
+	ActionFail   = "fail"
+
+	Redacted = "[REDACTED]"
+)
+
+type Finding struct {
+	DashboardUID string `json:"dashboardUID"`
+	Path         string `json:"path"`
+	Rule         string `json:"rule"`
+}
+
+type pattern struct {
+	name string
+	re   *regexp.Regexp
+}
+
+type Scanner struct {
+	patterns []pattern
+}
+
+// Patterns may use a named "secret" group to redact only part of the match.
+var builtinPatterns = []struct {
+	name string
+	expr string
+}{
+	{"url-credentials", `[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@$]+:(?P<secret>[^/\s@$][^/\s@]*)@`},
+	{"bearer-token", `(?i)\bbearer\s+(?P<secret>[A-Za-z0-9\-._~+/]{16,}=*)`},
+	{"query-credentials", `(?i)[?&](?:api[_-]?key|apikey|access[_-]?token|token|password|passwd|pwd|secret|client[_-]?secret|auth)=(?P<secret>[^$&\s"'#][^&\s"'#]*)`},
+	{"aws-access-key", `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{"github-token", `\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`},
+	{"gitlab-token", `\bglpat-[A-Za-z0-9\-_]{20,}\b`},
+	{"slack-token", `\bxox[abposr]-[A-Za-z0-9-]{10,}\b`},
+	{"grafana-token", `\b(?:glsa|glc)_[A-Za-z0-9_=+/]{32,}\b`},
+	{"private-key", `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?(?:-----END [A-Z ]*PRIVATE KEY-----|$)`},
+}
+
+var simpleKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func ValidAction(action string) bool {
+	switch action {
+	case ActionRedact, ActionSkip, ActionFail:
+		return true
+	default:
+		return false
+	}
+}
+
+func New(extraPatterns []string) (*Scanner, error) {
+	s := &Scanner{}
+	for _, p := range builtinPatterns {
+		s.patterns = append(s.patterns, pattern{name: p.name, re: regexp.MustCompile(p.expr)})
+	}
+
+	for i, expr := range extraPatterns {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret pattern %q: %w", expr, err)
+		}
+		s.patterns = append(s.patterns, pattern{name: fmt.Sprintf("custom-%d", i+1), re: re})
+	}
+
+	logger.Log.Debug().Int("patternCount", len(s.patterns)).Msg("Secret scanner initialized")
+	return s, nil
+}
+
+func LoadPatterns(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open secret patterns file: %w", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to close file")
+		}
+	}()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read secret patterns file: %w", err)
+	}
+	return patterns, nil
+}
+
+func (s *Scanner) Scan(data map[string]interface{}, redact bool) []Finding {
+	var findings []Finding
+	s.walk(data, "$", redact, &findings)
+	return findings
+}
+
+func (s *Scanner) walk(node interface{}, path string, redact bool, findings *[]Finding) interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(n))
+		for k := range n {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			n[k] = s.walk(n[k], childPath(path, k), redact, findings)
+		}
+		return n
+	case []interface{}:
+		for i := range n {
+			n[i] = s.walk(n[i], fmt.Sprintf("%s[%d]", path, i), redact, findings)
+		}
+		return n
+	case string:
+		return s.scanString(n, path, redact, findings)
+	default:
+		return node
+	}
+}
+
+func (s *Scanner) scanString(value, path string, redact bool, findings *[]Finding) string {
+	for _, p := range s.patterns {
+		if !p.re.MatchString(value) {
+			continue
+		}
+		*findings = append(*findings, Finding{Path: path, Rule: p.name})
+		if redact {
+			value = redactMatches(p.re, value)
+		}
+	}
+	return value
+}
+
+func redactMatches(re *regexp.Regexp, value string) string {
+	group := re.SubexpIndex("secret")
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringSubmatchIndex(value, -1) {
+		start, end := loc[0], loc[1]
+		if group > 0 && loc[2*group] >= 0 {
+			start, end = loc[2*group], loc[2*group+1]
+		}
+		b.WriteString(value[last:start])
+		b.WriteString(Redacted)
+		last = end
+	}
+	b.WriteString(value[last:])
+	return b.String()
+}
+
+func childPath(parent, key string) string {
+	if simpleKey.MatchString(key) {
+		return parent + "." + key
+	}
+	return fmt.Sprintf("%s[%q]", parent, key)
+}
@@ -0,0 +1,160 @@
+package signing
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	FormatOpenPGP = "openpgp"
+	FormatSSH     = "ssh"
+)
+
+// sshNamespace is the namespace git uses for SSH commit and tag signatures.
+const sshNamespace = "git"
+
+// Signer matches the signer interface of go-git commit and tag options.
+type Signer interface {
+	Sign(message io.Reader) ([]byte, error)
+}
+
+func ValidFormat(format string) bool {
+	switch format {
+	case FormatOpenPGP, FormatSSH:
+		return true
+	}
+	return false
+}
+
+// Load creates a signer for format from key, or from the file at keyPath if key is empty.
+func Load(format, keyPath, key, passphrase string) (Signer, error) {
+	raw := []byte(key)
+	if key == "" {
+		var err error
+		raw, err = os.ReadFile(keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signing key: %w", err)
+		}
+	}
+
+	switch format {
+	case FormatOpenPGP:
+		return NewOpenPGP(raw, passphrase)
+	case FormatSSH:
+		return NewSSH(raw, passphrase)
+	default:
+		return nil, fmt.Errorf("invalid signing format: %s", format)
+	}
+}
+
+type OpenPGPSigner struct {
+	entity *openpgp.Entity
+}
+
+// NewOpenPGP creates a signer from the first private key in an armored OpenPGP key ring.
+func NewOpenPGP(armoredKey []byte, passphrase string) (*OpenPGPSigner, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(armoredKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OpenPGP key: %w", err)
+	}
+
+	for _, entity := range entities {
+		if entity.PrivateKey == nil {
+			continue
+		}
+		if entity.PrivateKey.Encrypted {
+			if passphrase == "" {
+				return nil, fmt.Errorf("OpenPGP key is encrypted but no passphrase was given")
+			}
+			if err := entity.DecryptPrivateKeys([]byte(passphrase)); err != nil {
+				return nil, fmt.Errorf("failed to decrypt OpenPGP key: %w", err)
+			}
+		}
+		return &OpenPGPSigner{entity: entity}, nil
+	}
+	return nil, fmt.Errorf("OpenPGP key ring does not contain a private key")
+}
+
+func (s *OpenPGPSigner) Sign(message io.Reader) ([]byte, error) {
+	var b bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&b, s.entity, message, nil); err != nil {
+		return nil, fmt.Errorf("failed to sign with OpenPGP key: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+type SSHSigner struct {
+	signer ssh.Signer
+}
+
+func NewSSH(privateKey []byte, passphrase string) (*SSHSigner, error) {
+	var signer ssh.Signer
+	var err error
+	if passphrase == "" {
+		signer, err = ssh.ParsePrivateKey(privateKey)
+	} else {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(privateKey, []byte(passphrase))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH signing key: %w", err)
+	}
+	return &SSHSigner{signer: signer}, nil
+}
+
+// Sign creates an armored SSH signature (the SSHSIG format of ssh-keygen -Y sign) in the git namespace.
+func (s *SSHSigner) Sign(message io.Reader) ([]byte, error) {
+	h := sha512.New()
+	if _, err := io.Copy(h, message); err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+
+	signed := ssh.Marshal(struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          []byte
+	}{sshNamespace, "", "sha512", h.Sum(nil)})
+	signed = append([]byte("SSHSIG"), signed...)
+
+	var sig *ssh.Signature
+	var err error
+	if algSigner, ok := s.signer.(ssh.AlgorithmSigner); ok && s.signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		// Plain ssh-rsa (SHA-1) signatures are rejected by git.
+		sig, err = algSigner.SignWithAlgorithm(rand.Reader, signed, ssh.KeyAlgoRSASHA512)
+	} else {
+		sig, err = s.signer.Sign(rand.Reader, signed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign with SSH key: %w", err)
+	}
+
+	blob := ssh.Marshal(struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Signature     []byte
+	}{1, s.signer.PublicKey().Marshal(), sshNamespace, "", "sha512", ssh.Marshal(sig)})
+	blob = append([]byte("SSHSIG"), blob...)
+
+	encoded := base64.StdEncoding.EncodeToString(blob)
+	var b strings.Builder
+	b.WriteString("-----BEGIN SSH SIGNATURE-----\n")
+	for len(encoded) > 70 {
+		b.WriteString(encoded[:70] + "\n")
+		encoded = encoded[70:]
+	}
+	b.WriteString(encoded + "\n")
+	b.WriteString("-----END SSH SIGNATURE-----\n")
+	return []byte(b.String()), nil
+}
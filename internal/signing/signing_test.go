@@ -0,0 +1,218 @@
+package signing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"golang.org/x/crypto/ssh"
+)
+
+func armoredOpenPGPKey(t *testing.T, passphrase string) ([]byte, *openpgp.Entity) {
+	t.Helper()
+	entity, err := openpgp.NewEntity("Exporter", "", "exporter@example.com", nil)
+	if err != nil {
+		t.Fatalf("Failed to create OpenPGP key: %v", err)
+	}
+	if passphrase != "" {
+		if err := entity.EncryptPrivateKeys([]byte(passphrase), nil); err != nil {
+			t.Fatalf("Failed to encrypt OpenPGP key: %v", err)
+		}
+	}
+
+	var b bytes.Buffer
+	w, err := armor.Encode(&b, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatalf("Failed to armor OpenPGP key: %v", err)
+	}
+	if err := entity.SerializePrivateWithoutSigning(w, nil); err != nil {
+		t.Fatalf("Failed to serialize OpenPGP key: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to armor OpenPGP key: %v", err)
+	}
+	return b.Bytes(), entity
+}
+
+func TestNewOpenPGP(t *testing.T) {
+	plainKey, plainEntity := armoredOpenPGPKey(t, "")
+	encryptedKey, encryptedEntity := armoredOpenPGPKey(t, "secret")
+
+	tests := []struct {
+		name       string
+		key        []byte
+		passphrase string
+		entity     *openpgp.Entity
+		wantErr    bool
+	}{
+		{name: "Unencrypted key", key: plainKey, entity: plainEntity},
+		{name: "Encrypted key", key: encryptedKey, passphrase: "secret", entity: encryptedEntity},
+		{name: "Missing passphrase", key: encryptedKey, wantErr: true},
+		{name: "Wrong passphrase", key: encryptedKey, passphrase: "wrong", wantErr: true},
+		{name: "Not a key", key: []byte("not a key"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewOpenPGP(tt.key, tt.passphrase)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewOpenPGP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			message := "tree 1234\n\ncommit message\n"
+			signature, err := signer.Sign(strings.NewReader(message))
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+			if _, err := openpgp.CheckArmoredDetachedSignature(openpgp.EntityList{tt.entity}, strings.NewReader(message), bytes.NewReader(signature), nil); err != nil {
+				t.Errorf("Sign() produced an invalid signature: %v", err)
+			}
+		})
+	}
+}
+
+func verifySSHSignature(t *testing.T, armored []byte, message string) ssh.PublicKey {
+	t.Helper()
+	block := strings.TrimSpace(string(armored))
+	if !strings.HasPrefix(block, "-----BEGIN SSH SIGNATURE-----\n") || !strings.HasSuffix(block, "\n-----END SSH SIGNATURE-----") {
+		t.Fatalf("Sign() = %q, want an armored SSH signature", armored)
+	}
+	body := strings.TrimSuffix(strings.TrimPrefix(block, "-----BEGIN SSH SIGNATURE-----\n"), "\n-----END SSH SIGNATURE-----")
+	raw, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(body, "\n", ""))
+	if err != nil {
+		t.Fatalf("Failed to decode signature: %v", err)
+	}
+	if !bytes.HasPrefix(raw, []byte("SSHSIG")) {
+		t.Fatalf("Signature does not start with the SSHSIG magic")
+	}
+
+	var sig struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Signature     []byte
+	}
+	if err := ssh.Unmarshal(raw[6:], &sig); err != nil {
+		t.Fatalf("Failed to parse signature: %v", err)
+	}
+	if sig.Version != 1 || sig.Namespace != "git" || sig.HashAlgorithm != "sha512" {
+		t.Errorf("Signature header = %d %s %s", sig.Version, sig.Namespace, sig.HashAlgorithm)
+	}
+
+	pub, err := ssh.ParsePublicKey(sig.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to parse public key: %v", err)
+	}
+	var signature ssh.Signature
+	if err := ssh.Unmarshal(sig.Signature, &signature); err != nil {
+		t.Fatalf("Failed to parse signature blob: %v", err)
+	}
+
+	hash := sha512.Sum512([]byte(message))
+	signed := append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          []byte
+	}{"git", "", "sha512", hash[:]})...)
+	if err := pub.Verify(signed, &signature); err != nil {
+		t.Errorf("Signature does not verify: %v", err)
+	}
+	return pub
+}
+
+func TestNewSSH(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	edPEM, err := ssh.MarshalPrivateKeyWithPassphrase(edKey, "", []byte("secret"))
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	rsaPEM, err := ssh.MarshalPrivateKey(rsaKey, "")
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		key        []byte
+		passphrase string
+		wantAlgo   string
+		wantErr    bool
+	}{
+		{name: "Encrypted ed25519 key", key: pem.EncodeToMemory(edPEM), passphrase: "secret", wantAlgo: ssh.KeyAlgoED25519},
+		{name: "RSA key uses SHA-512", key: pem.EncodeToMemory(rsaPEM), wantAlgo: ssh.KeyAlgoRSASHA512},
+		{name: "Missing passphrase", key: pem.EncodeToMemory(edPEM), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewSSH(tt.key, tt.passphrase)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSSH() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			message := "tree 1234\n\ncommit message\n"
+			armored, err := signer.Sign(strings.NewReader(message))
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+			for _, line := range strings.Split(string(armored), "\n") {
+				if len(line) > 70 {
+					t.Errorf("Sign() line longer than 70 characters: %q", line)
+				}
+			}
+			verifySSHSignature(t, armored, message)
+
+			raw, _ := base64.StdEncoding.DecodeString(strings.ReplaceAll(strings.Split(string(armored), "-----")[2], "\n", ""))
+			if !bytes.Contains(raw, []byte(tt.wantAlgo)) {
+				t.Errorf("Sign() did not use %s", tt.wantAlgo)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	key, _ := armoredOpenPGPKey(t, "")
+	path := filepath.Join(t.TempDir(), "signing.asc")
+	if err := os.WriteFile(path, key, 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+
+	if _, err := Load(FormatOpenPGP, path, "", ""); err != nil {
+		t.Errorf("Load() from file error = %v", err)
+	}
+	if _, err := Load(FormatOpenPGP, "/nonexistent/key", string(key), ""); err != nil {
+		t.Errorf("Load() from key contents error = %v", err)
+	}
+	if _, err := Load(FormatSSH, path, "", ""); err == nil {
+		t.Errorf("Load() should return an error for an OpenPGP key in SSH format")
+	}
+	if _, err := Load("x509", path, "", ""); err == nil {
+		t.Errorf("Load() should return an error for an invalid format")
+	}
+}
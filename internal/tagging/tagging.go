@@ -0,0 +1,114 @@
+package tagging
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+
+	"grafana-db-exporter/internal/git"
+)
+
+const DefaultTemplate = "grafana-export-{{.Timestamp}}"
+
+type Data struct {
+	Time           time.Time
+	Timestamp      string
+	Date           string
+	Branch         string
+	RunID          string
+	GrafanaURL     string
+	GrafanaVersion string
+	Dashboards     int
+}
+
+type Template struct {
+	tmpl   *template.Template
+	prefix string
+}
+
+func NewData(now time.Time, branch, runID, grafanaURL, grafanaVersion string, dashboards int) Data {
+	return Data{
+		Time:           now,
+		Timestamp:      now.Format("20060102150405"),
+		Date:           now.Format("2006-01-02"),
+		Branch:         branch,
+		RunID:          runID,
+		GrafanaURL:     grafanaURL,
+		GrafanaVersion: grafanaVersion,
+		Dashboards:     dashboards,
+	}
+}
+
+// Parse parses text as a tag name template, using DefaultTemplate when text is empty.
+func Parse(text string) (*Template, error) {
+	if text == "" {
+		text = DefaultTemplate
+	}
+
+	tmpl, err := template.New("tag").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tag name template: %w", err)
+	}
+	prefix, _, _ := strings.Cut(text, "{{")
+	return &Template{tmpl: tmpl, prefix: prefix}, nil
+}
+
+// Prefix returns the literal text before the first template action, shared by every rendered tag name.
+func (t *Template) Prefix() string {
+	return t.prefix
+}
+
+func (t *Template) Name(data Data) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render tag name: %w", err)
+	}
+
+	name := strings.TrimSpace(b.String())
+	if name == "" || plumbing.NewTagReferenceName(name).Validate() != nil {
+		return "", fmt.Errorf("invalid tag name: %q", name)
+	}
+	return name, nil
+}
+
+func Message(data Data) string {
+	version := data.GrafanaVersion
+	if version == "" {
+		version = "unknown"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Grafana export %s\n\n", data.Time.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "Grafana-URL: %s\n", data.GrafanaURL)
+	fmt.Fprintf(&b, "Grafana-Version: %s\n", version)
+	fmt.Fprintf(&b, "Dashboards: %d\n", data.Dashboards)
+	fmt.Fprintf(&b, "Export-Branch: %s\n", data.Branch)
+	fmt.Fprintf(&b, "Export-Run-ID: %s\n", data.RunID)
+	return b.String()
+}
+
+// Expired returns the names of tags created longer ago than retention, oldest first. The tag named
+// keep is never returned, so the tag of the current run survives a clock skew.
+func Expired(tags []git.RemoteTag, retention time.Duration, now time.Time, keep string) []string {
+	if retention <= 0 {
+		return nil
+	}
+
+	var expired []git.RemoteTag
+	for _, t := range tags {
+		if t.Name != keep && !t.Created.IsZero() && now.Sub(t.Created) > retention {
+			expired = append(expired, t)
+		}
+	}
+	sort.SliceStable(expired, func(i, j int) bool { return expired[i].Created.Before(expired[j].Created) })
+
+	names := make([]string, 0, len(expired))
+	for _, t := range expired {
+		names = append(names, t.Name)
+	}
+	return names
+}
@@ -0,0 +1,100 @@
+package tagging
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"grafana-db-exporter/internal/git"
+)
+
+func TestTemplate_Name(t *testing.T) {
+	data := NewData(time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC), "main", "run-7", "https://grafana.example.com", "11.2.0", 42)
+
+	tests := []struct {
+		name       string
+		template   string
+		expected   string
+		wantPrefix string
+		wantErr    bool
+	}{
+		{name: "Default template", template: "", expected: "grafana-export-20261018093000", wantPrefix: "grafana-export-"},
+		{name: "Date and run ID", template: "backup/{{.Date}}-{{.RunID}}", expected: "backup/2026-10-18-run-7", wantPrefix: "backup/"},
+		{name: "Time format", template: `export-{{.Time.Format "2006.01.02"}}`, expected: "export-2026.10.18", wantPrefix: "export-"},
+		{name: "No static prefix", template: "{{.Date}}", expected: "2026-10-18", wantPrefix: ""},
+		{name: "Invalid tag name", template: "export {{.Date}}", wantErr: true, wantPrefix: "export "},
+		{name: "Empty tag name", template: "{{if false}}export{{end}}", wantErr: true},
+		{name: "Unknown field", template: "export-{{.Unknown}}", wantErr: true, wantPrefix: "export-"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse(tt.template)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if tmpl.Prefix() != tt.wantPrefix {
+				t.Errorf("Prefix() = %q, want %q", tmpl.Prefix(), tt.wantPrefix)
+			}
+			got, err := tmpl.Name(data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Name() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("Name() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	if _, err := Parse("export-{{.Date"); err == nil {
+		t.Error("Parse() expected error for unterminated action")
+	}
+}
+
+func TestMessage(t *testing.T) {
+	data := NewData(time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC), "main", "run-7", "https://grafana.example.com", "", 42)
+	expected := `Grafana export 2026-10-18T09:30:00Z
+
+Grafana-URL: https://grafana.example.com
+Grafana-Version: unknown
+Dashboards: 42
+Export-Branch: main
+Export-Run-ID: run-7
+`
+	if got := Message(data); got != expected {
+		t.Errorf("Message() = %q, want %q", got, expected)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	tags := []git.RemoteTag{
+		{Name: "export-new", Created: now.Add(-24 * time.Hour)},
+		{Name: "export-older", Created: now.Add(-60 * 24 * time.Hour)},
+		{Name: "export-old", Created: now.Add(-40 * 24 * time.Hour)},
+		{Name: "export-light"},
+		{Name: "export-current", Created: now.Add(-90 * 24 * time.Hour)},
+	}
+
+	tests := []struct {
+		name      string
+		retention time.Duration
+		expected  []string
+	}{
+		{name: "Retention disabled", retention: 0, expected: nil},
+		{name: "Thirty days", retention: 30 * 24 * time.Hour, expected: []string{"export-older", "export-old"}},
+		{name: "Fifty days", retention: 50 * 24 * time.Hour, expected: []string{"export-older"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Expired(tags, tt.retention, now, "export-current")
+			if len(got) == 0 && len(tt.expected) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expired() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,367 @@
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"grafana-db-exporter/internal/logger"
+)
+
+const (
+	OpSet     = "set"
+	OpDelete  = "delete"
+	OpReplace = "replace"
+
+	// RootFolder is the folder name used to scope rules to dashboards outside of any folder.
+	RootFolder = "General"
+)
+
+type Rule struct {
+	Op      string            `json:"op"`
+	Path    string            `json:"path"`
+	Value   interface{}       `json:"value,omitempty"`
+	Match   map[string]string `json:"match,omitempty"`
+	Folders []string          `json:"folders,omitempty"`
+	Tags    []string          `json:"tags,omitempty"`
+}
+
+type Pipeline struct {
+	rules []*compiledRule
+}
+
+type segmentKind int
+
+const (
+	segKey segmentKind = iota
+	segIndex
+	segWildcard
+)
+
+type segment struct {
+	kind  segmentKind
+	key   string
+	index int
+}
+
+type compiledRule struct {
+	Rule
+	segments []segment
+	match    map[string]*regexp.Regexp
+}
+
+func Load(path string) (*Pipeline, error) {
+	logger.Log.Debug().Str("path", path).Msg("Loading transform rules")
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transform rules: %w", err)
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse transform rules: %w", err)
+	}
+
+	return New(rules)
+}
+
+func New(rules []Rule) (*Pipeline, error) {
+	p := &Pipeline{}
+	for i, rule := range rules {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transform rule %d: %w", i, err)
+		}
+		p.rules = append(p.rules, compiled)
+	}
+	logger.Log.Debug().Int("ruleCount", len(p.rules)).Msg("Transform rules compiled")
+	return p, nil
+}
+
+func (p *Pipeline) Len() int {
+	return len(p.rules)
+}
+
+func (p *Pipeline) Apply(data map[string]interface{}, folder string) int {
+	if folder == "" {
+		folder = RootFolder
+	}
+	tags := dashboardTags(data)
+
+	changes := 0
+	for _, rule := range p.rules {
+		if !rule.inScope(folder, tags) {
+			continue
+		}
+		_, n := rule.apply(data, rule.segments)
+		if n > 0 {
+			logger.Log.Debug().Str("op", rule.Op).Str("path", rule.Path).Int("changes", n).Msg("Applied transform rule")
+		}
+		changes += n
+	}
+	return changes
+}
+
+func compileRule(rule Rule) (*compiledRule, error) {
+	switch rule.Op {
+	case OpSet, OpReplace:
+		if rule.Value == nil {
+			return nil, fmt.Errorf("%s rule requires a value", rule.Op)
+		}
+	case OpDelete:
+	default:
+		return nil, fmt.Errorf("unknown op %q", rule.Op)
+	}
+
+	segments, err := parsePath(rule.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled := &compiledRule{Rule: rule, segments: segments, match: make(map[string]*regexp.Regexp)}
+	for field, pattern := range rule.Match {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match pattern for %q: %w", field, err)
+		}
+		compiled.match[field] = re
+	}
+	return compiled, nil
+}
+
+// parsePath parses a JSONPath subset: $.key.nested[0].list[*].field
+func parsePath(path string) ([]segment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("path %q must start with $", path)
+	}
+
+	var segments []segment
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end == -1 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("path %q contains an empty key", path)
+			}
+			segments = append(segments, segment{kind: segKey, key: key})
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("path %q has an unterminated index", path)
+			}
+			inner := rest[1:end]
+			switch {
+			case inner == "*":
+				segments = append(segments, segment{kind: segWildcard})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				segments = append(segments, segment{kind: segKey, key: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("path %q has an invalid index %q", path, inner)
+				}
+				segments = append(segments, segment{kind: segIndex, index: index})
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("path %q has unexpected character %q", path, rest[0])
+		}
+	}
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("path %q does not select anything", path)
+	}
+	return segments, nil
+}
+
+func (r *compiledRule) inScope(folder string, tags map[string]bool) bool {
+	if len(r.Folders) > 0 {
+		found := false
+		for _, f := range r.Folders {
+			if f == folder {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(r.Tags) > 0 {
+		for _, tag := range r.Tags {
+			if tags[tag] {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+func (r *compiledRule) matches(node interface{}) bool {
+	if len(r.match) == 0 {
+		return true
+	}
+
+	obj, ok := node.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for field, re := range r.match {
+		value, ok := obj[field]
+		if !ok || !re.MatchString(stringify(value)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *compiledRule) apply(node interface{}, segments []segment) (interface{}, int) {
+	seg := segments[0]
+	last := len(segments) == 1
+
+	if seg.kind == segKey {
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return node, 0
+		}
+
+		child, exists := obj[seg.key]
+		if last {
+			switch {
+			case r.Op == OpSet && (!exists || r.matches(child)):
+				obj[seg.key] = Clone(r.Value)
+				return node, 1
+			case r.Op == OpReplace && exists && r.matches(child):
+				obj[seg.key] = Clone(r.Value)
+				return node, 1
+			case r.Op == OpDelete && exists && r.matches(child):
+				delete(obj, seg.key)
+				return node, 1
+			}
+			return node, 0
+		}
+
+		if !exists {
+			if r.Op != OpSet || segments[1].kind != segKey {
+				return node, 0
+			}
+			child = map[string]interface{}{}
+		}
+
+		newChild, n := r.apply(child, segments[1:])
+		if n > 0 {
+			obj[seg.key] = newChild
+		}
+		return node, n
+	}
+
+	arr, ok := node.([]interface{})
+	if !ok {
+		return node, 0
+	}
+
+	var indices []int
+	if seg.kind == segWildcard {
+		for i := range arr {
+			indices = append(indices, i)
+		}
+	} else if seg.index < len(arr) {
+		indices = []int{seg.index}
+	}
+
+	if !last {
+		changes := 0
+		for _, i := range indices {
+			var n int
+			arr[i], n = r.apply(arr[i], segments[1:])
+			changes += n
+		}
+		return arr, changes
+	}
+
+	if r.Op == OpDelete {
+		remove := make(map[int]bool)
+		for _, i := range indices {
+			if r.matches(arr[i]) {
+				remove[i] = true
+			}
+		}
+		if len(remove) == 0 {
+			return arr, 0
+		}
+		kept := make([]interface{}, 0, len(arr)-len(remove))
+		for i, item := range arr {
+			if !remove[i] {
+				kept = append(kept, item)
+			}
+		}
+		return kept, len(remove)
+	}
+
+	changes := 0
+	for _, i := range indices {
+		if r.matches(arr[i]) {
+			arr[i] = Clone(r.Value)
+			changes++
+		}
+	}
+	return arr, changes
+}
+
+func dashboardTags(data map[string]interface{}) map[string]bool {
+	tags := make(map[string]bool)
+	list, ok := data["tags"].([]interface{})
+	if !ok {
+		return tags
+	}
+	for _, t := range list {
+		if s, ok := t.(string); ok {
+			tags[s] = true
+		}
+	}
+	return tags
+}
+
+func stringify(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(raw)
+}
+
+// Clone returns a deep copy of a decoded JSON value.
+func Clone(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[k] = Clone(item)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, item := range v {
+			s[i] = Clone(item)
+		}
+		return s
+	default:
+		return v
+	}
+}
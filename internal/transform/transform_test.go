@@ -0,0 +1,216 @@
+package transform
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func parse(t *testing.T, raw string) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		t.Fatalf("Failed to parse test JSON: %v", err)
+	}
+	return m
+}
+
+func TestPipeline_Apply(t *testing.T) {
+	tests := []struct {
+		name        string
+		rules       []Rule
+		folder      string
+		input       string
+		expected    string
+		wantChanges int
+	}{
+		{
+			name:        "Set top-level field",
+			rules:       []Rule{{Op: OpSet, Path: "$.editable", Value: false}},
+			input:       `{"editable":true}`,
+			expected:    `{"editable":false}`,
+			wantChanges: 1,
+		},
+		{
+			name:        "Set creates missing intermediate objects",
+			rules:       []Rule{{Op: OpSet, Path: "$.graphTooltip.mode", Value: "shared"}},
+			input:       `{}`,
+			expected:    `{"graphTooltip":{"mode":"shared"}}`,
+			wantChanges: 1,
+		},
+		{
+			name:        "Set on every panel",
+			rules:       []Rule{{Op: OpSet, Path: "$.panels[*].transparent", Value: true}},
+			input:       `{"panels":[{"id":1},{"id":2}]}`,
+			expected:    `{"panels":[{"id":1,"transparent":true},{"id":2,"transparent":true}]}`,
+			wantChanges: 2,
+		},
+		{
+			name:        "Delete field",
+			rules:       []Rule{{Op: OpDelete, Path: "$.id"}},
+			input:       `{"id":42,"title":"t"}`,
+			expected:    `{"title":"t"}`,
+			wantChanges: 1,
+		},
+		{
+			name: "Delete matching panel links",
+			rules: []Rule{{
+				Op:    OpDelete,
+				Path:  "$.panels[*].links[*]",
+				Match: map[string]string{"url": `^https://tools\.internal/`},
+			}},
+			input: `{"panels":[{"id":1,"links":[{"url":"https://tools.internal/x"},{"url":"https://example.com"}]},
+				{"id":2,"links":[{"url":"https://tools.internal/y"}]}]}`,
+			expected:    `{"panels":[{"id":1,"links":[{"url":"https://example.com"}]},{"id":2,"links":[]}]}`,
+			wantChanges: 2,
+		},
+		{
+			name: "Delete specific annotation",
+			rules: []Rule{{
+				Op:    OpDelete,
+				Path:  "$.annotations.list[*]",
+				Match: map[string]string{"name": "^Deployments$"},
+			}},
+			input:       `{"annotations":{"list":[{"name":"Annotations & Alerts"},{"name":"Deployments"}]}}`,
+			expected:    `{"annotations":{"list":[{"name":"Annotations & Alerts"}]}}`,
+			wantChanges: 1,
+		},
+		{
+			name:        "Replace existing field",
+			rules:       []Rule{{Op: OpReplace, Path: "$.time", Value: map[string]interface{}{"from": "now-1h", "to": "now"}}},
+			input:       `{"time":{"from":"now-7d","to":"now"}}`,
+			expected:    `{"time":{"from":"now-1h","to":"now"}}`,
+			wantChanges: 1,
+		},
+		{
+			name:        "Replace skips missing field",
+			rules:       []Rule{{Op: OpReplace, Path: "$.refresh", Value: "1m"}},
+			input:       `{}`,
+			expected:    `{}`,
+			wantChanges: 0,
+		},
+		{
+			name: "Replace matching array element",
+			rules: []Rule{{
+				Op:    OpReplace,
+				Path:  "$.panels[*].datasource",
+				Value: map[string]interface{}{"uid": "${datasource}"},
+				Match: map[string]string{"uid": "^abc123$"},
+			}},
+			input:       `{"panels":[{"datasource":{"uid":"abc123"}},{"datasource":{"uid":"other"}}]}`,
+			expected:    `{"panels":[{"datasource":{"uid":"${datasource}"}},{"datasource":{"uid":"other"}}]}`,
+			wantChanges: 1,
+		},
+		{
+			name:        "Index path",
+			rules:       []Rule{{Op: OpSet, Path: "$.panels[1].title", Value: "Second"}},
+			input:       `{"panels":[{"title":"a"},{"title":"b"}]}`,
+			expected:    `{"panels":[{"title":"a"},{"title":"Second"}]}`,
+			wantChanges: 1,
+		},
+		{
+			name:        "Folder scope matches",
+			rules:       []Rule{{Op: OpSet, Path: "$.editable", Value: false, Folders: []string{"Production"}}},
+			folder:      "Production",
+			input:       `{"editable":true}`,
+			expected:    `{"editable":false}`,
+			wantChanges: 1,
+		},
+		{
+			name:        "Folder scope does not match",
+			rules:       []Rule{{Op: OpSet, Path: "$.editable", Value: false, Folders: []string{"Production"}}},
+			folder:      "Staging",
+			input:       `{"editable":true}`,
+			expected:    `{"editable":true}`,
+			wantChanges: 0,
+		},
+		{
+			name:        "Root folder scope",
+			rules:       []Rule{{Op: OpSet, Path: "$.editable", Value: false, Folders: []string{RootFolder}}},
+			input:       `{"editable":true}`,
+			expected:    `{"editable":false}`,
+			wantChanges: 1,
+		},
+		{
+			name:        "Tag scope matches",
+			rules:       []Rule{{Op: OpDelete, Path: "$.links", Tags: []string{"public"}}},
+			input:       `{"tags":["team-a","public"],"links":[]}`,
+			expected:    `{"tags":["team-a","public"]}`,
+			wantChanges: 1,
+		},
+		{
+			name:        "Tag scope does not match",
+			rules:       []Rule{{Op: OpDelete, Path: "$.links", Tags: []string{"public"}}},
+			input:       `{"tags":["team-a"],"links":[]}`,
+			expected:    `{"tags":["team-a"],"links":[]}`,
+			wantChanges: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline, err := New(tt.rules)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			data := parse(t, tt.input)
+			changes := pipeline.Apply(data, tt.folder)
+			if changes != tt.wantChanges {
+				t.Errorf("Apply() changes = %d, want %d", changes, tt.wantChanges)
+			}
+
+			expected := parse(t, tt.expected)
+			if !reflect.DeepEqual(data, expected) {
+				got, _ := json.Marshal(data)
+				t.Errorf("Apply() = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNew_InvalidRules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{name: "Unknown op", rule: Rule{Op: "move", Path: "$.a"}},
+		{name: "Set without value", rule: Rule{Op: OpSet, Path: "$.a"}},
+		{name: "Path without root", rule: Rule{Op: OpDelete, Path: "a.b"}},
+		{name: "Empty path", rule: Rule{Op: OpDelete, Path: "$"}},
+		{name: "Invalid index", rule: Rule{Op: OpDelete, Path: "$.a[x]"}},
+		{name: "Unterminated index", rule: Rule{Op: OpDelete, Path: "$.a[0"}},
+		{name: "Invalid match pattern", rule: Rule{Op: OpDelete, Path: "$.a", Match: map[string]string{"b": "("}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New([]Rule{tt.rule}); err == nil {
+				t.Errorf("New() expected error for rule %+v", tt.rule)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "rules.json")
+	rules := `[{"op":"set","path":"$.editable","value":false},{"op":"delete","path":"$.id"}]`
+	if err := os.WriteFile(path, []byte(rules), 0644); err != nil {
+		t.Fatalf("Failed to write rules file: %v", err)
+	}
+
+	pipeline, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if pipeline.Len() != 2 {
+		t.Errorf("Load() rule count = %d, want 2", pipeline.Len())
+	}
+
+	if _, err := Load(filepath.Join(tempDir, "missing.json")); err == nil {
+		t.Errorf("Load() expected error for missing file")
+	}
+}
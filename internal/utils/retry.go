@@ -2,6 +2,7 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -18,6 +19,23 @@ func (e *OperationError) Error() string {
 	return fmt.Sprintf("%s failed: %v", e.Operation, e.Err)
 }
 
+// PermanentError marks an error that retrying cannot fix.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
 func Retry[T any](ctx context.Context, cfg *config.Config, operation string, fn func() (T, error)) (T, error) {
 	var result T
 	var err error
@@ -45,6 +63,12 @@ func Retry[T any](ctx context.Context, cfg *config.Config, operation string, fn
 				return result, nil
 			}
 
+			var permanent *PermanentError
+			if errors.As(err, &permanent) {
+				logger.Log.Error().Err(err).Uint("attempt", i+1).Msgf("%s failed, not retrying", operation)
+				return result, &OperationError{Operation: operation, Err: err}
+			}
+
 			logger.Log.Error().Err(err).Uint("attempt", i+1).Uint("max_attempts", cfg.NumOfRetries).Msgf("%s failed, retrying...", operation)
 
 			if i < cfg.NumOfRetries-1 {
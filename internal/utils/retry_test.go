@@ -77,6 +77,21 @@ func TestRetry(t *testing.T) {
 			expectedError:  true,
 			expectedCalls:  3,
 		},
+		{
+			name: "Permanent error is not retried",
+			cfg: &config.Config{
+				EnableRetries:  true,
+				NumOfRetries:   3,
+				RetriesBackoff: 1,
+			},
+			operation: "test operation",
+			fn: func() (interface{}, error) {
+				return nil, Permanent(errors.New("conflict"))
+			},
+			expectedResult: nil,
+			expectedError:  true,
+			expectedCalls:  1,
+		},
 		{
 			name: "Retries disabled",
 			cfg: &config.Config{